@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bwangelme/cosp/pkg"
+
+	"github.com/spf13/cobra"
+)
+
+var ACLCmd = &cobra.Command{
+	Use:   "acl",
+	Short: "查看和修改文件的访问权限",
+	Long: `查看和修改 COS 中文件的访问权限。
+
+访问权限可以是:
+  private      私有，只有 bucket 所有者和授权用户可以访问
+  public-read  公有读，所有人都可以通过文件地址访问
+  default      继承 bucket 的访问权限
+
+私有文件会额外输出 1 小时内有效的临时访问地址。
+
+示例:
+  cosp acl get drafts/2024-01-15-143022.png           # 查看文件的访问权限
+  cosp acl set drafts/2024-01-15-143022.png private   # 将文件设置为私有
+  cosp upload --acl private draft.png                 # 上传时设置访问权限`,
+}
+
+var aclGetCmd = &cobra.Command{
+	Use:   "get <文件名>",
+	Short: "查看文件的访问权限",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		key := args[0]
+		client, _, err := pkg.NewClientWithFallback()
+		if err != nil {
+			log.Fatalf("创建COS客户端失败: %v", err)
+		}
+		result, err := client.GetObjectACL(cmd.Context(), key)
+		if err != nil {
+			log.Fatalf("获取 %s 的访问权限失败: %v", key, err)
+		}
+
+		fmt.Printf("文件: %s\n", key)
+		if result.Owner != nil {
+			fmt.Printf("所有者: %s\n", result.Owner.ID)
+		}
+		public := pkg.IsPublicRead(result)
+		if public {
+			fmt.Println("访问权限: 公有读")
+		} else {
+			fmt.Println("访问权限: 私有或继承 bucket 的权限")
+		}
+
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "授权对象\t权限")
+		fmt.Fprintln(w, "----\t----")
+		for _, grant := range result.AccessControlList {
+			grantee := "-"
+			if grant.Grantee != nil {
+				grantee = strings.Join(nonEmpty(grant.Grantee.URI, grant.Grantee.ID, grant.Grantee.DisplayName), " ")
+			}
+			fmt.Fprintf(w, "%s\t%s\n", grantee, grant.Permission)
+		}
+		w.Flush()
+
+		fmt.Printf("\n文件地址: %s\n", client.Config.ObjectURL(key))
+		if !public && !client.Config.Anonymous {
+			signed, err := client.PresignedURL(cmd.Context(), http.MethodGet, key, defaultPresignExpires)
+			if err != nil {
+				log.Fatalf("生成临时访问地址失败: %v", err)
+			}
+			printSignedURL(signed, defaultPresignExpires)
+		}
+	},
+}
+
+var aclSetCmd = &cobra.Command{
+	Use:   "set <文件名> <private|public-read|default>",
+	Short: "修改文件的访问权限",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		key := args[0]
+		value, err := pkg.ParseACL(args[1])
+		if err != nil {
+			log.Fatalf("访问权限无效: %v", err)
+		}
+
+		client, _, err := pkg.NewClientWithFallback()
+		if err != nil {
+			log.Fatalf("创建COS客户端失败: %v", err)
+		}
+		// COS 返回 404 时给出更明确的提示
+		exists, err := client.ObjectExists(cmd.Context(), key)
+		if err != nil {
+			log.Fatalf("检查文件是否存在失败: %v", err)
+		}
+		if !exists {
+			log.Fatalf("文件不存在: %s", key)
+		}
+		if err := client.SetObjectACL(cmd.Context(), key, value); err != nil {
+			log.Fatalf("修改 %s 的访问权限失败: %v", key, err)
+		}
+		fmt.Printf("✅ 已将 %s 的访问权限修改为 %s\n", key, value)
+
+		if value == pkg.ACLPrivate {
+			signed, err := client.PresignedURL(cmd.Context(), http.MethodGet, key, defaultPresignExpires)
+			if err != nil {
+				log.Fatalf("生成临时访问地址失败: %v", err)
+			}
+			printSignedURL(signed, defaultPresignExpires)
+		}
+	},
+}
+
+// nonEmpty 返回参数中不为空的字符串
+func nonEmpty(values ...string) []string {
+	var result []string
+	for _, value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func init() {
+	ACLCmd.AddCommand(aclGetCmd)
+	ACLCmd.AddCommand(aclSetCmd)
+}
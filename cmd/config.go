@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bwangelme/cosp/pkg"
+
+	"github.com/spf13/cobra"
+)
+
+var validateCheck bool
+
+// initFields config init 依次询问的配置项，required 为 true 的配置项不能为空
+var initFields = []struct {
+	name     string
+	prompt   string
+	required bool
+}{
+	{"credential_process", "获取密钥的外部命令（可选，设置后不需要 SecretId 和 SecretKey）", false},
+	{"secret_id", "SecretId", true},
+	{"secret_key", "SecretKey", true},
+	{"bucket", "存储桶名称（如 images-1250000000）", true},
+	{"region", "存储桶所在地域（如 ap-beijing）", true},
+	{"max_thread", "分块上传最大并发数", false},
+	{"part_size", "分块大小（MB）", false},
+	{"retry", "失败重试次数", false},
+	{"timeout", "超时时间（秒）", false},
+	{"schema", "协议（http/https）", false},
+	{"verify", "上传校验方式（md5/crc64/none）", false},
+}
+
+// secretFields 显示时需要隐藏的配置项
+var secretFields = map[string]bool{
+	"secret_id":     true,
+	"secret_key":    true,
+	"session_token": true,
+	"serve_token":   true,
+}
+
+var ConfigCmd = &cobra.Command{
+	Use:   "config",
+	Short: "创建、查看和检查配置文件",
+	Long: `创建、查看和检查配置文件。
+
+示例:
+  cosp config init                    # 交互式创建配置文件
+  cosp --profile blog config init     # 创建或修改 [blog] 配置节
+  cosp config show                    # 查看合并环境变量后实际生效的配置
+  cosp config validate --check        # 检查配置，并访问 bucket 确认密钥可用`,
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "交互式创建配置文件",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		configPath, err := pkg.WritableConfigPath()
+		if err != nil {
+			log.Fatalf("获取配置文件路径失败: %v", err)
+		}
+		profile, err := pkg.ResolveProfile()
+		if err != nil {
+			log.Fatalf("读取配置文件失败: %v", err)
+		}
+
+		// 已有配置时，使用当前的值作为默认值。不读取环境变量，避免把环境变量中的密钥写入配置文件
+		config, err := pkg.ReadConfigFile()
+		if err != nil {
+			config = pkg.DefaultConfig()
+		}
+
+		fmt.Printf("配置文件: %s\n", configPath)
+		fmt.Printf("配置节: [%s]\n", profile)
+		fmt.Println("直接回车使用方括号中的默认值")
+		fmt.Println()
+
+		reader := bufio.NewReader(os.Stdin)
+		var keys []string
+		values := map[string]string{}
+		for _, field := range initFields {
+			// 使用 credential_process 时不需要密钥
+			if secretFields[field.name] && config.CredentialProcess != "" {
+				continue
+			}
+			for {
+				value, err := prompt(cmd.Context(), reader, field.prompt, config.Value(field.name), secretFields[field.name])
+				if err != nil {
+					log.Fatalf("读取输入失败: %v", err)
+				}
+				// 匿名模式下不需要密钥
+				if value == "" && field.required && !(config.Anonymous && secretFields[field.name]) {
+					fmt.Println("  该字段不能为空")
+					continue
+				}
+				if err := config.Set(field.name, value); err != nil {
+					fmt.Printf("  输入无效: %v\n", err)
+					continue
+				}
+				keys = append(keys, field.name)
+				values[field.name] = config.Value(field.name)
+				break
+			}
+		}
+
+		// 配置有问题时默认不保存，否则默认保存
+		problems := config.Validate()
+		if len(problems) > 0 {
+			fmt.Println("\n配置存在以下问题:")
+			for _, problem := range problems {
+				fmt.Printf("  - %s\n", problem)
+			}
+			fmt.Print("\n仍然保存？(y/N): ")
+		} else {
+			fmt.Printf("\n保存到 %s [%s]？(Y/n): ", configPath, profile)
+		}
+		answer, _ := readLine(cmd.Context(), reader)
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+		case "":
+			if len(problems) > 0 {
+				fmt.Println("取消保存")
+				return
+			}
+		default:
+			fmt.Println("取消保存")
+			return
+		}
+
+		if err := pkg.SaveProfile(configPath, profile, keys, values); err != nil {
+			log.Fatalf("保存配置失败: %v", err)
+		}
+		fmt.Printf("✅ 配置已保存到 %s\n", configPath)
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "查看实际生效的配置，密钥会被隐藏",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		config, err := pkg.ReadConfig()
+		if err != nil {
+			log.Fatalf("读取配置失败: %v", err)
+		}
+
+		configFile := config.ConfigFile
+		if configFile == "" {
+			configFile = "（未使用配置文件）"
+		}
+		fmt.Printf("配置文件: %s\n", configFile)
+		fmt.Printf("配置节: [%s]\n\n", config.Profile)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, name := range pkg.ConfigKeys() {
+			value := config.Value(name)
+			if secretFields[name] {
+				value = maskSecret(value)
+			}
+			if os.Getenv("COS_"+strings.ToUpper(name)) != "" {
+				value += "\t（环境变量 COS_" + strings.ToUpper(name) + "）"
+			}
+			fmt.Fprintf(w, "%s\t= %s\n", name, value)
+		}
+		w.Flush()
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "检查配置是否完整、取值是否合理",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		config, err := pkg.ReadConfig()
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+
+		if problems := config.Validate(); len(problems) > 0 {
+			fmt.Println("❌ 配置存在以下问题:")
+			for _, problem := range problems {
+				fmt.Printf("  - %s\n", problem)
+			}
+			os.Exit(1)
+		}
+		fmt.Println("✅ 配置检查通过")
+
+		if !validateCheck {
+			return
+		}
+
+		client, err := pkg.NewClient(config)
+		if err != nil {
+			log.Fatalf("❌ 创建COS客户端失败: %v", err)
+		}
+		if err := client.HeadBucket(cmd.Context()); err != nil {
+			log.Fatalf("❌ 访问 bucket %s 失败: %v", config.Bucket, err)
+		}
+		fmt.Printf("✅ 成功访问 bucket %s\n", config.Bucket)
+	},
+}
+
+// prompt 输出提示并读取一行输入，输入为空时返回默认值
+func prompt(ctx context.Context, reader *bufio.Reader, label, defaultValue string, secret bool) (string, error) {
+	shown := defaultValue
+	if secret {
+		shown = maskSecret(defaultValue)
+	}
+	if shown != "" {
+		fmt.Printf("%s [%s]: ", label, shown)
+	} else {
+		fmt.Printf("%s: ", label)
+	}
+
+	read := readLine
+	if secret {
+		read = readSecret
+	}
+	line, err := read(ctx, reader)
+	if err != nil && line == "" {
+		return "", err
+	}
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return defaultValue, nil
+	}
+	return line, nil
+}
+
+// maskSecret 隐藏密钥，只保留最后 4 个字符
+func maskSecret(value string) string {
+	if len(value) <= 8 {
+		return strings.Repeat("*", len(value))
+	}
+	return strings.Repeat("*", len(value)-4) + value[len(value)-4:]
+}
+
+func init() {
+	configValidateCmd.Flags().BoolVar(&validateCheck, "check", false, "访问 bucket 确认密钥和 bucket 配置可用")
+
+	ConfigCmd.AddCommand(configInitCmd)
+	ConfigCmd.AddCommand(configShowCmd)
+	ConfigCmd.AddCommand(configValidateCmd)
+}
@@ -1,17 +1,17 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
 	"io"
-	"log"
-	"strings"
+	"os"
 
 	"github.com/bwangelme/cosp/pkg"
 
 	"github.com/spf13/cobra"
 )
 
+var deleteYes bool
+
 var DeleteCmd = &cobra.Command{
 	Use:   "delete [文件名...]",
 	Short: "根据文件名删除腾讯云 COS 中的文件",
@@ -19,63 +19,98 @@ var DeleteCmd = &cobra.Command{
 
 示例:
   cosp delete filename.txt         # 删除指定文件名的文件
-  cosp delete file1.txt file2.jpg  # 删除多个文件`,
+  cosp delete file1.txt file2.jpg  # 删除多个文件
+  cosp delete -y filename.txt      # 不询问确认，直接删除
+
+使用 --output 输出结构化结果时不会询问确认，需要同时指定 -y。`,
 	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		// 结构化输出供脚本使用，不能交互确认
+		if StructuredOutput() && !deleteYes {
+			Fatalf(ExitUsage, "使用 --output 时需要同时指定 -y 确认删除")
+		}
+
 		// 创建 COS 客户端
 		client, _, err := pkg.NewClientWithFallback()
 		if err != nil {
-			log.Fatalf("创建COS客户端失败: %v", err)
+			Fatalf(ExitUsage, "创建COS客户端失败: %v", err)
+		}
+		if err := client.CheckWritable(); err != nil {
+			Fatalf(ExitUsage, "无法删除文件: %v", err)
 		}
 
 		// 解析要删除的文件名
 		var fileNames []string
 		for _, arg := range args {
 			fileNames = append(fileNames, arg)
-			fmt.Printf("待删除文件: %s\n", arg)
+			fmt.Fprintf(messageOutput, "待删除文件: %s\n", arg)
 		}
 
 		if len(fileNames) == 0 {
-			log.Fatalf("没有找到要删除的文件")
+			Fatalf(ExitUsage, "没有找到要删除的文件")
 		}
 
-		// 确认删除
-		fmt.Printf("\n即将删除 %d 个文件:\n", len(fileNames))
+		records := make([]record, 0, len(fileNames))
 		for _, fileName := range fileNames {
-			fmt.Printf("  - %s\n", fileName)
+			records = append(records, record{Key: fileName, URL: client.Config.ObjectURL(fileName), Status: recordSkipped})
 		}
 
-		fmt.Print("\n确认删除？(y/N): ")
-		var confirm string
-		fmt.Scanln(&confirm)
-		if strings.ToLower(confirm) != "y" && strings.ToLower(confirm) != "yes" {
-			fmt.Println("取消删除操作")
-			return
+		// 确认删除
+		if !deleteYes {
+			fmt.Fprintf(messageOutput, "\n即将删除 %d 个文件:\n", len(fileNames))
+			for _, fileName := range fileNames {
+				fmt.Fprintf(messageOutput, "  - %s\n", fileName)
+			}
+
+			fmt.Fprint(messageOutput, "\n确认删除？(y/N): ")
+			if !confirm(cmd.Context()) {
+				fmt.Fprintln(messageOutput, "取消删除操作")
+				return
+			}
 		}
 
 		// 删除文件
 		successCount := 0
-		for _, fileName := range fileNames {
-			fmt.Printf("正在删除: %s ... ", fileName)
-			resp, err := client.Object.Delete(context.Background(), fileName)
+		for i, fileName := range fileNames {
+			if cmd.Context().Err() != nil {
+				fmt.Fprintln(messageOutput, "\n已取消删除操作")
+				break
+			}
+			fmt.Fprintf(messageOutput, "正在删除: %s ... ", fileName)
+			records[i].Status = recordFailed
+			resp, err := client.DeleteObject(cmd.Context(), fileName)
 			if err != nil {
-				fmt.Printf("失败: %v\n", err)
+				fmt.Fprintf(messageOutput, "失败: %v\n", err)
+				records[i].Error = err.Error()
 			} else if resp.StatusCode != 200 && resp.StatusCode != 204 {
 				// 删除成功通常返回200或204，其他状态码表示失败
-				fmt.Printf("失败 (状态码: %d)\n", resp.StatusCode)
+				fmt.Fprintf(messageOutput, "失败 (状态码: %d)\n", resp.StatusCode)
+				records[i].Error = fmt.Sprintf("状态码: %d", resp.StatusCode)
 				if resp.Body != nil {
 					bodyBytes, readErr := io.ReadAll(resp.Body)
 					if readErr == nil {
-						fmt.Printf("错误详情: %s\n", string(bodyBytes))
+						fmt.Fprintf(messageOutput, "错误详情: %s\n", string(bodyBytes))
 					}
 					resp.Body.Close()
 				}
 			} else {
-				fmt.Println("成功")
+				fmt.Fprintln(messageOutput, "成功")
+				records[i].Status = recordDeleted
 				successCount++
 			}
 		}
 
-		fmt.Printf("\n删除完成，成功删除 %d 个文件\n", successCount)
+		fmt.Fprintf(messageOutput, "\n删除完成，成功删除 %d 个文件\n", successCount)
+		if StructuredOutput() {
+			printRecords(records)
+		}
+		if successCount < len(fileNames) {
+			os.Exit(ExitFailure)
+		}
 	},
 }
+
+func init() {
+	SupportStructuredOutput(DeleteCmd)
+	DeleteCmd.Flags().BoolVarP(&deleteYes, "yes", "y", false, "不询问确认，直接删除")
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bwangelme/cosp/pkg"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	downloadOut      string
+	downloadNoResume bool
+)
+
+var DownloadCmd = &cobra.Command{
+	Use:   "download <文件名|编号>...",
+	Short: "下载腾讯云 COS 中的文件",
+	Long: `下载腾讯云 COS 中的文件。
+
+参数可以是文件名、以 / 结尾的前缀，或者 cosp list 输出的编号。前缀会下载该前缀下的所有
+文件，并在目标目录中保留前缀之后的目录结构。
+
+只下载一个文件时 -o 指定保存的文件路径（-o 为已存在的目录或以 / 结尾时保存到该目录中），
+下载多个文件时 -o 指定保存的目录，默认为当前目录。
+
+大文件按 part_size 分块，使用 max_thread 个协程并发下载。下载中的数据先写入
+<文件名>.cospart，中断后再次运行相同的命令会从已下载的分块继续。
+
+示例:
+  cosp download 2024-01-15-143022.png              # 下载到当前目录
+  cosp download 3 -o cover.png                     # 下载 cosp list 中编号为 3 的文件
+  cosp download 1 2 5 -o ./images                  # 下载多个文件到 images 目录
+  cosp download 2024/01/ -o ./backup               # 下载前缀下的所有文件`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		client, _, err := pkg.NewClientWithFallback()
+		if err != nil {
+			log.Fatalf("创建COS客户端失败: %v", err)
+		}
+
+		var (
+			tasks             []downloadTask
+			succeeded, failed int
+		)
+		for _, arg := range args {
+			argTasks, err := resolveDownloadArg(cmd, client, arg, len(args) == 1)
+			if err != nil {
+				fmt.Printf("❌ %s: %v\n", arg, err)
+				failed++
+				continue
+			}
+			tasks = append(tasks, argTasks...)
+		}
+
+		for _, task := range tasks {
+			size, err := client.DownloadFile(cmd.Context(), task.key, task.path, !downloadNoResume)
+			if cmd.Context().Err() != nil {
+				fmt.Println("\n下载已取消，再次运行相同的命令可以继续下载")
+				os.Exit(1)
+			}
+			if err != nil {
+				fmt.Printf("❌ 下载失败: %s: %v\n", task.key, err)
+				failed++
+				continue
+			}
+			fmt.Printf("✅ 下载完成: %s -> %s (%s)\n", task.key, task.path, formatSize(size))
+			succeeded++
+		}
+
+		if succeeded+failed > 1 {
+			fmt.Printf("\n共 %d 个文件，成功 %d 个，失败 %d 个\n", succeeded+failed, succeeded, failed)
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// downloadTask 待下载的文件及其保存路径
+type downloadTask struct {
+	key  string
+	path string
+}
+
+// resolveDownloadArg 将命令行参数转换为待下载的文件，single 表示只有一个参数
+//
+// 参数为数字时从 cosp list 保存的编号中查找文件名；以 / 结尾，或者文件不存在但存在
+// 以 <参数>/ 开头的文件时作为前缀下载。
+func resolveDownloadArg(cmd *cobra.Command, client *pkg.Client, arg string, single bool) ([]downloadTask, error) {
+	key := arg
+	if index, err := strconv.Atoi(arg); err == nil && index > 0 {
+		if key, err = getFileNameFromTempFile(index); err != nil {
+			return nil, err
+		}
+	}
+	key = strings.TrimLeft(key, "/")
+
+	if !strings.HasSuffix(key, "/") {
+		exists, err := client.ObjectExists(cmd.Context(), key)
+		if err != nil {
+			return nil, fmt.Errorf("检查文件是否存在失败: %v", err)
+		}
+		if exists {
+			target, err := downloadPath(key, single)
+			if err != nil {
+				return nil, err
+			}
+			return []downloadTask{{key: key, path: target}}, nil
+		}
+		key += "/"
+	}
+
+	objects, err := client.ListAllObjects(cmd.Context(), key)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件列表失败: %v", err)
+	}
+	dir := downloadOut
+	if dir == "" {
+		dir = "."
+	}
+	var tasks []downloadTask
+	for _, obj := range objects {
+		// 跳过控制台创建目录时生成的空对象
+		if strings.HasSuffix(obj.Key, "/") {
+			continue
+		}
+		target, err := localPath(dir, strings.TrimPrefix(obj.Key, key))
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, downloadTask{key: obj.Key, path: target})
+	}
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("文件不存在")
+	}
+	return tasks, nil
+}
+
+// downloadPath 返回单个文件的保存路径
+func downloadPath(key string, single bool) (string, error) {
+	name := path.Base(key)
+	if downloadOut == "" {
+		return localPath(".", name)
+	}
+	if !single || strings.HasSuffix(downloadOut, "/") || strings.HasSuffix(downloadOut, string(filepath.Separator)) {
+		return localPath(downloadOut, name)
+	}
+	if stat, err := os.Stat(downloadOut); err == nil && stat.IsDir() {
+		return localPath(downloadOut, name)
+	}
+	return downloadOut, nil
+}
+
+// localPath 将 COS 中的相对路径转换为 dir 下的本地路径，拒绝包含 .. 的路径
+func localPath(dir, rel string) (string, error) {
+	for _, segment := range strings.Split(rel, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("文件名 %s 包含 ..，拒绝下载到目标目录之外", rel)
+		}
+	}
+	return filepath.Join(dir, filepath.FromSlash(rel)), nil
+}
+
+func init() {
+	DownloadCmd.Flags().StringVarP(&downloadOut, "out", "o", "", "保存的文件路径或目录，默认为当前目录")
+	DownloadCmd.Flags().BoolVar(&downloadNoResume, "no-resume", false, "不使用上次中断时已下载的分块，重新下载")
+}
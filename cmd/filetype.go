@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"mime"
+
+	"github.com/h2non/filetype"
+	"github.com/h2non/filetype/types"
+)
+
+// sniffSize 识别文件类型时读取的字节数，SVG 需要读取到 <svg 标签
+const sniffSize = 4096
+
+// svgContentType SVG 文件的 Content-Type，filetype 无法识别 SVG
+const svgContentType = "image/svg+xml"
+
+// sniffImage 根据文件开头的内容识别图片类型，返回 Content-Type，不是图片或 SVG 时返回 false
+func sniffImage(head []byte) (string, bool) {
+	if filetype.IsImage(head) {
+		kind, _ := filetype.Match(head)
+		return kind.MIME.Value, true
+	}
+	if isSVGContent(string(head)) {
+		return svgContentType, true
+	}
+	return "", false
+}
+
+// contentTypeByExtension 根据扩展名返回 Content-Type，如 png 返回 image/png，未知的扩展名返回空字符串
+func contentTypeByExtension(ext string) string {
+	if ext == "svg" {
+		return svgContentType
+	}
+	if t := filetype.GetType(ext); t != filetype.Unknown {
+		return t.MIME.Value
+	}
+	return mime.TypeByExtension("." + ext)
+}
+
+// extensionByType 根据 Content-Type 返回扩展名，未知的类型返回空字符串
+func extensionByType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	if mediaType == svgContentType {
+		return ".svg"
+	}
+
+	ext := ""
+	filetype.Types.Range(func(_, value any) bool {
+		if t := value.(types.Type); t.MIME.Value == mediaType {
+			ext = "." + t.Extension
+			return false
+		}
+		return true
+	})
+	if ext != "" {
+		return ext
+	}
+	if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
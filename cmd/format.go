@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/bwangelme/cosp/pkg"
+
+	"github.com/spf13/cobra"
+)
+
+// upload、paste 和 list 共用的输出格式参数
+var (
+	linkFormat   string
+	linkTemplate string
+)
+
+// newLinkFormatter 根据 --format、--template 参数和配置项 default_format、format_template 创建 LinkFormatter
+//
+// 只指定 --template 时使用 custom 格式。
+func newLinkFormatter(config *pkg.COSConfig) (*pkg.LinkFormatter, error) {
+	format, custom := config.DefaultFormat, config.FormatTemplate
+	if linkTemplate != "" {
+		format, custom = pkg.FormatCustom, linkTemplate
+	}
+	if linkFormat != "" {
+		value, err := pkg.ParseLinkFormat(linkFormat)
+		if err != nil {
+			return nil, fmt.Errorf("--format 无效: %v", err)
+		}
+		format = value
+	}
+	return pkg.NewLinkFormatter(format, custom)
+}
+
+// addFormatFlags 添加输出格式参数
+func addFormatFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&linkFormat, "format", "", "输出文件地址的格式: url, markdown, html, bbcode, org, rst, custom，默认使用配置项 default_format")
+	cmd.Flags().StringVar(&linkTemplate, "template", "", "custom 格式使用的 Go 模板，如 '![{{.Name}}]({{.URL}})'，默认使用配置项 format_template")
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// readLine 从 reader 读取一行输入，等待输入时按下 Ctrl-C 以 ExitInterrupted 退出
+//
+// 整个进程的 Ctrl-C 都用于取消 ctx，阻塞在读取标准输入时无法响应，这里同时等待 ctx 被取消。
+func readLine(ctx context.Context, reader *bufio.Reader) (string, error) {
+	type result struct {
+		line string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		line, err := reader.ReadString('\n')
+		done <- result{line: line, err: err}
+	}()
+	select {
+	case r := <-done:
+		return r.line, r.err
+	case <-ctx.Done():
+		interrupted()
+		return "", ctx.Err()
+	}
+}
+
+// readSecret 与 readLine 相同，但标准输入为终端时不回显输入的内容
+func readSecret(ctx context.Context, reader *bufio.Reader) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return readLine(ctx, reader)
+	}
+	state, err := term.GetState(fd)
+	if err != nil {
+		return "", err
+	}
+	type result struct {
+		line string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		line, err := term.ReadPassword(fd)
+		done <- result{line: string(line), err: err}
+	}()
+	select {
+	case r := <-done:
+		// 不回显时换行也不会显示
+		fmt.Println()
+		return r.line, r.err
+	case <-ctx.Done():
+		// 恢复回显，避免退出后终端不显示输入
+		term.Restore(fd, state)
+		interrupted()
+		return "", ctx.Err()
+	}
+}
+
+// interrupted 等待输入时按下 Ctrl-C，以 ExitInterrupted 退出
+func interrupted() {
+	fmt.Fprintln(messageOutput, "\n已取消")
+	os.Exit(ExitInterrupted)
+}
+
+// confirm 读取一行标准输入，输入 y 或 yes 时返回 true
+func confirm(ctx context.Context) bool {
+	line, _ := readLine(ctx, bufio.NewReader(os.Stdin))
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
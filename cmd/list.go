@@ -2,8 +2,8 @@ package cmd
 
 import (
 	"bufio"
-	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/user"
@@ -38,9 +38,13 @@ var ListCmd = &cobra.Command{
   cosp list --marker 10        # 从第10个文件开始列出`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// 创建 COS 客户端
-		client, bucketURL, err := pkg.NewClientWithFallback()
+		client, _, err := pkg.NewClientWithFallback()
 		if err != nil {
-			log.Fatalf("创建COS客户端失败: %v", err)
+			Fatalf(ExitUsage, "创建COS客户端失败: %v", err)
+		}
+		formatter, err := newLinkFormatter(client.Config)
+		if err != nil {
+			Fatalf(ExitUsage, "%v", err)
 		}
 
 		// 处理 marker 参数：如果是数字，则从临时文件中读取对应的文件名
@@ -50,13 +54,13 @@ var ListCmd = &cobra.Command{
 				// marker 是数字，从临时文件中读取对应的文件名
 				actualMarker, err = getFileNameFromTempFile(markerNum)
 				if err != nil {
-					log.Fatalf("无法从临时文件中找到第%d个文件: %v", markerNum, err)
+					Fatalf(ExitUsage, "无法从临时文件中找到第%d个文件: %v", markerNum, err)
 				}
-				fmt.Printf("使用编号 %d，对应的文件名为: %s\n", markerNum, actualMarker)
+				fmt.Fprintf(messageOutput, "使用编号 %d，对应的文件名为: %s\n", markerNum, actualMarker)
 			} else {
 				// marker 是文件名，直接使用
 				actualMarker = marker
-				fmt.Printf("使用文件名作为 marker: %s\n", actualMarker)
+				fmt.Fprintf(messageOutput, "使用文件名作为 marker: %s\n", actualMarker)
 			}
 		}
 
@@ -74,19 +78,27 @@ var ListCmd = &cobra.Command{
 		}
 
 		// 获取文件列表
-		result, _, err := client.Bucket.Get(context.Background(), opts)
+		result, err := client.ListObjects(cmd.Context(), opts)
 		if err != nil {
-			log.Fatalf("获取文件列表失败: %v", err)
+			Fatalf(ExitFailure, "获取文件列表失败: %v", err)
 		}
 
 		// 检查是否有文件
+		if len(result.Contents) == 0 && StructuredOutput() {
+			printRecords(nil)
+			return
+		}
 		if len(result.Contents) == 0 {
 			fmt.Println("没有找到文件")
 			return
 		}
 
-		// 创建表格输出
-		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		// 创建表格输出，结构化输出时不输出表格
+		var out io.Writer = os.Stdout
+		if StructuredOutput() {
+			out = io.Discard
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
 
 		// 输出表头
 		fmt.Fprintln(w, "编号\t文件名\t大小\t最后修改时间\t文件地址")
@@ -100,7 +112,10 @@ var ListCmd = &cobra.Command{
 		}
 
 		// 准备要写入临时文件的数据
-		var tempFileData []string
+		var (
+			tempFileData []string
+			records      []record
+		)
 
 		// 输出文件信息
 		for i, obj := range result.Contents {
@@ -116,8 +131,12 @@ var ListCmd = &cobra.Command{
 			// 格式化时间
 			timeStr := lastModified.Format("2006-01-02 15:04:05")
 
-			// 构建完整的文件地址
-			fileURL := fmt.Sprintf("%s/%s", bucketURL, obj.Key)
+			// 按 --format 生成文件地址
+			objectURL := client.Config.ObjectURL(obj.Key)
+			fileURL, err := formatter.Format(pkg.NewLink(objectURL, obj.Key, "", obj.Size))
+			if err != nil {
+				Fatalf(ExitUsage, "%v", err)
+			}
 
 			// 当前文件的编号
 			currentIndex := startIndex + i
@@ -127,9 +146,14 @@ var ListCmd = &cobra.Command{
 
 			// 准备写入临时文件的数据
 			tempFileData = append(tempFileData, fmt.Sprintf("%d\t%s", currentIndex, obj.Key))
+
+			records = append(records, objectRecord(client.Config, obj))
 		}
 
 		w.Flush()
+		if StructuredOutput() {
+			printRecords(records)
+		}
 
 		// 将文件名和编号存储到临时文件中
 		err = saveToTempFile(tempFileData, true) // 每次都覆盖临时文件
@@ -138,15 +162,27 @@ var ListCmd = &cobra.Command{
 		}
 
 		// 输出分页信息
-		fmt.Printf("\n总共 %d 个文件（按创建时间反向排序）", len(result.Contents))
+		fmt.Fprintf(messageOutput, "\n总共 %d 个文件（按创建时间反向排序）", len(result.Contents))
 		if result.IsTruncated {
 			nextIndex := startIndex + len(result.Contents)
-			fmt.Printf("，还有更多文件，使用 --marker %d 继续查看", nextIndex)
+			fmt.Fprintf(messageOutput, "，还有更多文件，使用 --marker %d 继续查看", nextIndex)
 		}
-		fmt.Println()
+		fmt.Fprintln(messageOutput)
 	},
 }
 
+// objectRecord 将 COS 中的文件转换为结构化输出的记录
+func objectRecord(config *pkg.COSConfig, obj cos.Object) record {
+	return record{
+		Key:          obj.Key,
+		URL:          config.ObjectURL(obj.Key),
+		Size:         obj.Size,
+		ETag:         strings.Trim(obj.ETag, `"`),
+		LastModified: obj.LastModified,
+		Status:       recordOK,
+	}
+}
+
 // formatSize 格式化文件大小
 func formatSize(size int64) string {
 	if size < 1024 {
@@ -253,8 +289,10 @@ func getStartIndexFromTempFile(fileName string) (int, error) {
 }
 
 func init() {
+	SupportStructuredOutput(ListCmd)
 	// 添加命令行参数
 	ListCmd.Flags().IntVarP(&maxKeys, "max-keys", "n", 20, "最大返回文件数量")
 	ListCmd.Flags().StringVarP(&prefix, "prefix", "p", "", "文件名前缀过滤")
 	ListCmd.Flags().StringVarP(&marker, "marker", "m", "", "从指定文件名或编号开始列出")
+	addFormatFlags(ListCmd)
 }
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bwangelme/cosp/pkg"
+
+	"github.com/spf13/cobra"
+	"github.com/tencentyun/cos-go-sdk-v5"
+)
+
+var (
+	multipartPrefix    string
+	multipartAbortAll  bool
+	multipartOlderThan time.Duration
+)
+
+var MultipartCmd = &cobra.Command{
+	Use:   "multipart",
+	Short: "管理 COS 中未完成的分块上传",
+	Long: `管理 COS 中未完成的分块上传。
+
+中断的大文件上传会在 COS 中留下未完成的分块，这些分块会占用存储空间。
+可以使用 list 查看，使用 abort 舍弃不再需要的分块上传。
+
+示例:
+  cosp multipart list                          # 列出未完成的分块上传
+  cosp multipart abort <文件名> <UploadId>     # 舍弃指定的分块上传
+  cosp multipart abort --all --older-than 24h  # 舍弃 24 小时前开始的所有分块上传`,
+}
+
+var multipartListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出未完成的分块上传",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, _, err := pkg.NewClientWithFallback()
+		if err != nil {
+			log.Fatalf("创建COS客户端失败: %v", err)
+		}
+
+		uploads, err := client.ListMultipartUploads(cmd.Context(), multipartPrefix)
+		if err != nil {
+			log.Fatalf("获取分块上传列表失败: %v", err)
+		}
+		if len(uploads) == 0 {
+			fmt.Println("没有未完成的分块上传")
+			return
+		}
+
+		// 标记本地有断点记录、可以继续上传的分块上传
+		resumable := map[string]string{}
+		if checkpoints, err := pkg.ListCheckpoints(); err == nil {
+			for _, cp := range checkpoints {
+				resumable[cp.UploadID] = cp.FilePath
+			}
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "文件名\tUploadId\t开始时间\t本地文件")
+		fmt.Fprintln(w, "----\t--------\t--------\t--------")
+		for _, upload := range uploads {
+			localFile := resumable[upload.UploadID]
+			if localFile == "" {
+				localFile = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", upload.Key, upload.UploadID, formatInitiated(upload.Initiated), localFile)
+		}
+		w.Flush()
+
+		fmt.Printf("\n总共 %d 个未完成的分块上传\n", len(uploads))
+	},
+}
+
+var multipartAbortCmd = &cobra.Command{
+	Use:   "abort [文件名 UploadId]",
+	Short: "舍弃未完成的分块上传",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if multipartAbortAll {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(2)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		client, _, err := pkg.NewClientWithFallback()
+		if err != nil {
+			log.Fatalf("创建COS客户端失败: %v", err)
+		}
+		if err := client.CheckWritable(); err != nil {
+			log.Fatalf("无法舍弃分块上传: %v", err)
+		}
+
+		var uploads []cos.ListUploadsResultUpload
+		if multipartAbortAll {
+			all, err := client.ListMultipartUploads(cmd.Context(), multipartPrefix)
+			if err != nil {
+				log.Fatalf("获取分块上传列表失败: %v", err)
+			}
+			for _, upload := range all {
+				initiated, err := time.Parse(time.RFC3339, upload.Initiated)
+				if multipartOlderThan > 0 && err == nil && time.Since(initiated) < multipartOlderThan {
+					continue
+				}
+				uploads = append(uploads, upload)
+			}
+		} else {
+			uploads = append(uploads, cos.ListUploadsResultUpload{Key: args[0], UploadID: args[1]})
+		}
+
+		if len(uploads) == 0 {
+			fmt.Println("没有需要舍弃的分块上传")
+			return
+		}
+
+		fmt.Printf("即将舍弃 %d 个分块上传:\n", len(uploads))
+		for _, upload := range uploads {
+			fmt.Printf("  - %s (%s)\n", upload.Key, upload.UploadID)
+		}
+
+		fmt.Print("\n确认舍弃？(y/N): ")
+		if !confirm(cmd.Context()) {
+			fmt.Println("取消舍弃操作")
+			return
+		}
+
+		successCount := 0
+		for _, upload := range uploads {
+			if cmd.Context().Err() != nil {
+				fmt.Println("\n已取消舍弃操作")
+				break
+			}
+			fmt.Printf("正在舍弃: %s ... ", upload.Key)
+			if err := client.AbortMultipartUpload(cmd.Context(), upload.Key, upload.UploadID); err != nil {
+				fmt.Printf("失败: %v\n", err)
+				continue
+			}
+			if err := pkg.RemoveCheckpointsByUploadID(upload.UploadID); err != nil {
+				fmt.Printf("成功，但删除本地断点记录失败: %v\n", err)
+			} else {
+				fmt.Println("成功")
+			}
+			successCount++
+		}
+
+		fmt.Printf("\n舍弃完成，成功舍弃 %d 个分块上传\n", successCount)
+	},
+}
+
+// formatInitiated 格式化分块上传的开始时间
+func formatInitiated(initiated string) string {
+	t, err := time.Parse(time.RFC3339, initiated)
+	if err != nil {
+		return initiated
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+func init() {
+	MultipartCmd.PersistentFlags().StringVarP(&multipartPrefix, "prefix", "p", "", "文件名前缀过滤")
+	multipartAbortCmd.Flags().BoolVar(&multipartAbortAll, "all", false, "舍弃所有未完成的分块上传")
+	multipartAbortCmd.Flags().DurationVar(&multipartOlderThan, "older-than", 0, "配合 --all 使用，只舍弃开始时间早于该时长的分块上传，如 24h")
+
+	MultipartCmd.AddCommand(multipartListCmd)
+	MultipartCmd.AddCommand(multipartAbortCmd)
+}
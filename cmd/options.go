@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	logger "github.com/bwangelme/cosp/log"
+	"github.com/bwangelme/cosp/pkg"
+
+	"github.com/atotto/clipboard"
+	"github.com/spf13/cobra"
+)
+
+// upload 和 paste 共用的参数
+var (
+	keyTemplate string
+	exactKey    string
+	dedup       bool
+	onConflict  string
+
+	contentType        string
+	cacheControl       string
+	contentDisposition string
+	expires            string
+	storageClass       string
+	metas              []string
+	acl                string
+	presign            bool
+	presignExpires     time.Duration
+	copyResult         bool
+)
+
+// uploadSettings 根据命令行参数和配置确定的上传方式，同一次命令上传的所有文件共用
+type uploadSettings struct {
+	dedup  bool
+	policy string
+	// options 上传参数，ContentType 为空时按文件内容识别
+	options *pkg.UploadOptions
+	// presign 是否输出临时访问地址，而不是文件地址
+	presign bool
+	// presignExpires 临时访问地址的有效期
+	presignExpires time.Duration
+	// formatter 按 --format 生成输出的文件地址
+	formatter *pkg.LinkFormatter
+	// copy 上传后是否将文件地址复制到剪切板
+	copy bool
+}
+
+// newUploadSettings 合并命令行参数和配置，生成上传方式
+func newUploadSettings(cmd *cobra.Command, config *pkg.COSConfig) (*uploadSettings, error) {
+	dedupMode := dedupEnabled(cmd, config)
+	policy, err := conflictPolicy(config, dedupMode)
+	if err != nil {
+		return nil, err
+	}
+	options, err := uploadOptions(config)
+	if err != nil {
+		return nil, err
+	}
+	expires := defaultPresignExpires
+	if cmd.Flags().Changed("presign-expires") {
+		if presignExpires <= 0 {
+			return nil, fmt.Errorf("--presign-expires 无效: 有效期必须大于 0")
+		}
+		expires = presignExpires
+	}
+	formatter, err := newLinkFormatter(config)
+	if err != nil {
+		return nil, err
+	}
+	return &uploadSettings{
+		dedup:          dedupMode,
+		policy:         policy,
+		options:        options,
+		presign:        presign,
+		presignExpires: expires,
+		formatter:      formatter,
+		copy:           copyEnabled(cmd, config),
+	}, nil
+}
+
+// fileOptions 返回单个文件的上传参数，没有指定 --content-type 时使用识别到的 Content-Type
+func (s *uploadSettings) fileOptions(detected string) *pkg.UploadOptions {
+	opt := *s.options
+	if opt.ContentType == "" {
+		opt.ContentType = detected
+	}
+	return &opt
+}
+
+// dedupEnabled 判断是否使用去重模式，--dedup 参数优先于配置项 dedup，使用 --key 时不去重
+func dedupEnabled(cmd *cobra.Command, config *pkg.COSConfig) bool {
+	if exactKey != "" {
+		return false
+	}
+	if cmd.Flags().Changed("dedup") {
+		return dedup
+	}
+	return config.Dedup
+}
+
+// copyEnabled 判断上传后是否复制文件地址，--copy 参数优先于配置项 copy_result
+func copyEnabled(cmd *cobra.Command, config *pkg.COSConfig) bool {
+	if cmd.Flags().Changed("copy") {
+		return copyResult
+	}
+	return config.CopyResult
+}
+
+// copyLinks 将上传后的文件地址复制到剪切板，多个地址之间使用换行分隔
+func (s *uploadSettings) copyLinks(links []string) {
+	if !s.copy || len(links) == 0 {
+		return
+	}
+	if err := clipboard.WriteAll(strings.Join(links, "\n")); err != nil {
+		logger.L.Warnf("复制到剪切板失败: %v", err)
+		return
+	}
+	fmt.Fprintln(messageOutput, "📋 已复制到剪切板")
+}
+
+// conflictPolicy 返回同名文件的处理方式，--on-conflict 参数优先于配置项 on_conflict。
+// 去重模式下同名文件的内容一定相同，总是跳过上传
+func conflictPolicy(config *pkg.COSConfig, dedup bool) (string, error) {
+	if dedup {
+		return pkg.ConflictSkip, nil
+	}
+	if onConflict == "" {
+		return config.OnConflict, nil
+	}
+	policy, err := pkg.ParseConflictPolicy(onConflict)
+	if err != nil {
+		return "", fmt.Errorf("--on-conflict 无效: %v", err)
+	}
+	return policy, nil
+}
+
+// uploadOptions 返回上传参数，命令行参数优先于配置文件中的默认值，--meta 与配置项 meta 合并
+func uploadOptions(config *pkg.COSConfig) (*pkg.UploadOptions, error) {
+	now := time.Now()
+	opt := config.UploadOptions(now)
+	opt.ContentType = contentType
+	if cacheControl != "" {
+		opt.CacheControl = cacheControl
+	}
+	if contentDisposition != "" {
+		opt.ContentDisposition = contentDisposition
+	}
+	if expires != "" {
+		value, err := pkg.ParseExpires(expires, now)
+		if err != nil {
+			return nil, fmt.Errorf("--expires 无效: %v", err)
+		}
+		opt.Expires = value
+	}
+	if storageClass != "" {
+		class, err := pkg.ParseStorageClass(storageClass)
+		if err != nil {
+			return nil, fmt.Errorf("--storage-class 无效: %v", err)
+		}
+		opt.StorageClass = class
+	}
+	meta, err := pkg.ParseMeta(metas...)
+	if err != nil {
+		return nil, fmt.Errorf("--meta 无效: %v", err)
+	}
+	for key, value := range meta {
+		opt.Meta[key] = value
+	}
+	if acl != "" {
+		value, err := pkg.ParseACL(acl)
+		if err != nil {
+			return nil, fmt.Errorf("--acl 无效: %v", err)
+		}
+		opt.ACL = value
+	}
+	return opt, nil
+}
+
+// objectURLs 返回上传后输出的文件地址，以及私有文件额外输出的临时访问地址
+//
+// 使用 --presign 时文件地址为临时访问地址；没有使用 --presign 但文件为私有时，
+// 额外返回临时访问地址。临时访问地址在 --presign-expires 内有效。
+func (s *uploadSettings) objectURLs(ctx context.Context, client *pkg.Client, key string) (string, string, error) {
+	objectURL := client.Config.ObjectURL(key)
+	if !s.presign && s.options.ACL != pkg.ACLPrivate {
+		return objectURL, "", nil
+	}
+	signed, err := client.PresignedURL(ctx, http.MethodGet, key, s.presignExpires)
+	if err != nil {
+		return objectURL, "", fmt.Errorf("生成临时访问地址失败: %v", err)
+	}
+	if s.presign {
+		return signed, "", nil
+	}
+	return objectURL, signed, nil
+}
+
+// addUploadFlags 添加 upload 和 paste 共用的参数
+func addUploadFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&keyTemplate, "key-template", "", "生成文件名的模板，如 {year}/{month}/{md5:8}{ext}，默认使用配置项 key_template")
+	cmd.Flags().StringVar(&exactKey, "key", "", "直接指定上传后的文件名，忽略 key 模板")
+	cmd.Flags().BoolVar(&dedup, "dedup", false, "按文件内容去重，COS 中已有相同内容的文件时跳过上传，默认使用配置项 dedup")
+	cmd.Flags().StringVar(&onConflict, "on-conflict", "", "COS 中已存在同名文件时的处理方式: fail, rename, overwrite, skip，默认使用配置项 on_conflict")
+	cmd.MarkFlagsMutuallyExclusive("key", "key-template")
+	cmd.MarkFlagsMutuallyExclusive("key", "dedup")
+
+	cmd.Flags().StringVar(&contentType, "content-type", "", "指定上传文件的 Content-Type，默认根据文件内容识别")
+	cmd.Flags().StringVar(&cacheControl, "cache-control", "", "设置 Cache-Control，如 max-age=31536000，默认使用配置项 cache_control")
+	cmd.Flags().StringVar(&contentDisposition, "content-disposition", "", "设置 Content-Disposition，如 inline，默认使用配置项 content_disposition")
+	cmd.Flags().StringVar(&expires, "expires", "", "设置 Expires，可以是 HTTP 日期或时长，如 720h、30d，默认使用配置项 expires")
+	cmd.Flags().StringVar(&storageClass, "storage-class", "", "存储类型，如 STANDARD、STANDARD_IA、ARCHIVE，默认使用配置项 storage_class")
+	cmd.Flags().StringArrayVar(&metas, "meta", nil, "自定义元数据 key=value，可以指定多次，与配置项 meta 合并")
+	cmd.Flags().StringVar(&acl, "acl", "", "文件的访问权限: private, public-read, default（继承 bucket 的权限），private 时额外输出临时访问地址")
+	cmd.Flags().BoolVar(&presign, "presign", false, "输出带签名的临时访问地址而不是文件地址")
+	cmd.Flags().DurationVar(&presignExpires, "presign-expires", defaultPresignExpires, "临时访问地址的有效期，如 30m、24h，对 --presign 和私有文件额外输出的临时访问地址生效")
+
+	addFormatFlags(cmd)
+	cmd.Flags().BoolVar(&copyResult, "copy", false, "上传后将文件地址（按 --format 格式化）复制到剪切板，默认使用配置项 copy_result")
+}
@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+
+	logger "github.com/bwangelme/cosp/log"
+
+	"github.com/spf13/cobra"
+)
+
+// 全局参数 --output 支持的输出格式
+const (
+	OutputText  = "text"
+	OutputJSON  = "json"
+	OutputJSONL = "jsonl"
+	OutputYAML  = "yaml"
+)
+
+// 命令的退出状态码
+const (
+	// ExitFailure 操作失败，如部分文件上传失败、请求 COS 失败
+	ExitFailure = 1
+	// ExitUsage 参数或配置不正确
+	ExitUsage = 2
+	// ExitInterrupted 等待输入时按下了 Ctrl-C，与 shell 中被 SIGINT 终止的状态码相同
+	ExitInterrupted = 130
+)
+
+// 结构化输出中 status 字段的取值
+const (
+	recordUploaded = "uploaded"
+	recordExists   = "exists"
+	recordSkipped  = "skipped"
+	recordFailed   = "failed"
+	recordDeleted  = "deleted"
+	recordOK       = "ok"
+)
+
+// structuredAnnotation 支持 --output 的命令在 Annotations 中设置的键
+const structuredAnnotation = "cosp.structured-output"
+
+var outputFormat = OutputText
+
+// messageOutput 提示信息的输出位置，结构化输出时改为标准错误，保证标准输出中只有记录
+var messageOutput io.Writer = os.Stdout
+
+// SetOutputFormat 设置输出格式，结构化输出时日志和提示信息改为输出到标准错误
+func SetOutputFormat(format string) error {
+	switch strings.ToLower(format) {
+	case "", OutputText:
+		outputFormat = OutputText
+		return nil
+	case OutputJSON, OutputJSONL, OutputYAML:
+		outputFormat = strings.ToLower(format)
+	default:
+		return fmt.Errorf("--output 无效: %s，可选值: json, jsonl, yaml", format)
+	}
+	redirectMessages()
+	return nil
+}
+
+// redirectMessages 将日志和提示信息改为输出到标准错误
+func redirectMessages() {
+	messageOutput = os.Stderr
+	logger.L.SetOutput(os.Stderr)
+}
+
+// StructuredOutput 是否使用 json、jsonl 或 yaml 输出
+func StructuredOutput() bool {
+	return outputFormat != OutputText
+}
+
+// SupportStructuredOutput 标记命令支持 --output，没有标记的命令使用 --output 时报错
+func SupportStructuredOutput(cmd *cobra.Command) {
+	if cmd.Annotations == nil {
+		cmd.Annotations = map[string]string{}
+	}
+	cmd.Annotations[structuredAnnotation] = "true"
+}
+
+// CheckStructuredOutput 命令不支持结构化输出却使用了 --output 时以 ExitUsage 退出，
+// 避免脚本把人类可读的文本当作结构化结果解析
+func CheckStructuredOutput(cmd *cobra.Command) {
+	if !StructuredOutput() {
+		return
+	}
+	if _, ok := cmd.Annotations[structuredAnnotation]; !ok {
+		Fatalf(ExitUsage, "%s 不支持 --output，支持的命令: upload, paste, list, delete, version", cmd.CommandPath())
+	}
+}
+
+// record 结构化输出中的一条记录，字段名保持稳定，供脚本和编辑器插件解析
+//
+// key 到 error 的字段总是输出，没有值时为空字符串或 0；path 和 signed_url 只在有值时输出。
+type record struct {
+	Key          string `json:"key"`
+	URL          string `json:"url"`
+	Size         int64  `json:"size"`
+	ETag         string `json:"etag"`
+	LastModified string `json:"last_modified"`
+	Status       string `json:"status"`
+	Error        string `json:"error"`
+	// Path 上传的本地文件路径
+	Path string `json:"path,omitempty"`
+	// SignedURL 私有文件的临时访问地址
+	SignedURL string `json:"signed_url,omitempty"`
+}
+
+// errorRecord 结构化输出时写入标准错误的错误信息
+type errorRecord struct {
+	Error string `json:"error"`
+	Code  int    `json:"code"`
+}
+
+// printRecords 按 --output 输出多条记录：json 输出数组，jsonl 每行一条，yaml 输出列表
+func printRecords(records []record) {
+	if records == nil {
+		records = []record{}
+	}
+	PrintRecord(records)
+}
+
+// PrintRecord 按 --output 输出记录，v 为结构体或结构体的切片
+func PrintRecord(v any) {
+	if err := writeRecord(os.Stdout, outputFormat, v); err != nil {
+		Fatalf(ExitFailure, "输出结果失败: %v", err)
+	}
+}
+
+// Fatalf 输出错误信息并以 code 退出，结构化输出时错误以一行 JSON 写入标准错误
+func Fatalf(code int, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	if !StructuredOutput() {
+		log.Print(message)
+		os.Exit(code)
+	}
+	data, err := marshalJSON(errorRecord{Error: message, Code: code})
+	if err != nil {
+		data = []byte(fmt.Sprintf("{\"error\":%q,\"code\":%d}", message, code))
+	}
+	os.Stderr.Write(append(data, '\n'))
+	os.Exit(code)
+}
+
+func writeRecord(w io.Writer, format string, v any) error {
+	switch format {
+	case OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case OutputJSONL:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice {
+			return writeJSONLine(w, v)
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if err := writeJSONLine(w, rv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case OutputYAML:
+		return writeYAML(w, v)
+	}
+	return fmt.Errorf("不支持的输出格式: %s", format)
+}
+
+func writeJSONLine(w io.Writer, v any) error {
+	data, err := marshalJSON(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// marshalJSON 与 json.Marshal 相同，但不转义 URL 中的 &、< 和 >
+func marshalJSON(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// writeYAML 将结构体或结构体的切片写为 YAML
+//
+// 只支持字段为字符串、数字和布尔值的结构体，字段名取自 json 标签。
+// 标量使用 JSON 编码，JSON 字符串同时也是合法的 YAML 双引号字符串。
+func writeYAML(w io.Writer, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return writeYAMLMapping(w, rv, "", "")
+	}
+	if rv.Len() == 0 {
+		_, err := fmt.Fprintln(w, "[]")
+		return err
+	}
+	for i := 0; i < rv.Len(); i++ {
+		if err := writeYAMLMapping(w, rv.Index(i), "- ", "  "); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeYAMLMapping 输出结构体的字段，第一行使用 first 作为前缀，其余行使用 rest
+func writeYAMLMapping(w io.Writer, rv reflect.Value, first, rest string) error {
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("不支持输出 %s 类型的 YAML", rv.Kind())
+	}
+	prefix := first
+	for i := 0; i < rv.NumField(); i++ {
+		name, options, _ := strings.Cut(rv.Type().Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		field := rv.Field(i)
+		if options == "omitempty" && field.IsZero() {
+			continue
+		}
+		value, err := marshalJSON(field.Interface())
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%s%s: %s\n", prefix, name, value); err != nil {
+			return err
+		}
+		prefix = rest
+	}
+	return nil
+}
+
+// recordStatus 将上传状态转换为结构化输出中的 status
+func recordStatus(status string) string {
+	switch status {
+	case statusUploaded:
+		return recordUploaded
+	case statusExists:
+		return recordExists
+	case statusSkipped:
+		return recordSkipped
+	}
+	return recordFailed
+}
+
+// errorString 返回错误信息，err 为 nil 时返回空字符串
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteYAML(t *testing.T) {
+	r := record{
+		Key:    "a: #b.png",
+		URL:    "https://example.com/a.png?x=1&y=<2>",
+		Size:   42,
+		Status: recordUploaded,
+		Error:  "换行\n\"引号\"",
+	}
+
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{
+			"单条记录",
+			errorRecord{Error: "失败", Code: 2},
+			"error: \"失败\"\ncode: 2\n",
+		},
+		{
+			"空列表",
+			[]record{},
+			"[]\n",
+		},
+		{
+			"记录列表",
+			[]record{r, {Key: "b.png", Path: "/tmp/b.png"}},
+			"- key: \"a: #b.png\"\n" +
+				"  url: \"https://example.com/a.png?x=1&y=<2>\"\n" +
+				"  size: 42\n" +
+				"  etag: \"\"\n" +
+				"  last_modified: \"\"\n" +
+				"  status: \"" + recordUploaded + "\"\n" +
+				"  error: \"换行\\n\\\"引号\\\"\"\n" +
+				"- key: \"b.png\"\n" +
+				"  url: \"\"\n" +
+				"  size: 0\n" +
+				"  etag: \"\"\n" +
+				"  last_modified: \"\"\n" +
+				"  status: \"\"\n" +
+				"  error: \"\"\n" +
+				"  path: \"/tmp/b.png\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeYAML(&buf, tt.v); err != nil {
+				t.Fatalf("writeYAML 失败: %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("writeYAML 输出:\n%s\n期望:\n%s", got, tt.want)
+			}
+		})
+	}
+
+	if err := writeYAML(&bytes.Buffer{}, "a"); err == nil {
+		t.Error("输出字符串时应该返回错误")
+	}
+}
@@ -32,49 +32,112 @@ var PasteCmd = &cobra.Command{
 支持的平台：
 - macOS: 使用 Cmd+Shift+Ctrl+4 截图到剪切板，或复制 SVG 文本
 - Linux: 使用 xclip 复制图片到剪切板，或复制 SVG 文本
-- Windows: 降级为 base64 文本方式，或复制 SVG 文本`,
+- Windows: 降级为 base64 文本方式，或复制 SVG 文本
+
+文件名的生成方式与 cosp upload 相同，剪切板内容的 {filename} 为上传时间，如 2024-01-15-143022.png`,
 	Run: func(cmd *cobra.Command, args []string) {
-		b, fileExtension, isSVG, err := getClipboardContent()
+		client, bucketURL, err := pkg.NewClientWithFallback()
 		if err != nil {
-			logger.L.Errorf("读取剪切板失败: %v", err)
-			return
+			pasteFailed(ExitUsage, "创建 COS 客户端失败: %v", err)
 		}
+		logger.L.Debugf("成功连接到 COS，bucket URL: %s，文件地址前缀: %s", client.Config.GetBucketURL(), bucketURL)
 
-		logger.L.Debugf("准备上传 %s 文件，数据大小: %d 字节", func() string {
-			if isSVG {
-				return "SVG"
-			}
-			return "图片"
-		}(), len(b))
-
-		client, bucketURL, err := pkg.NewClientWithFallback()
+		settings, err := newUploadSettings(cmd, client.Config)
 		if err != nil {
-			logger.L.Errorf("创建 COS 客户端失败: %v", err)
-			return
+			pasteFailed(ExitUsage, "%v", err)
 		}
-		logger.L.Debugf("成功连接到 COS，bucket URL: %s", bucketURL)
-
-		timestamp := time.Now().Format("2006-01-02-150405")
-		objectKey := fmt.Sprintf("%s.%s", timestamp, fileExtension)
-		logger.L.Debugf("生成文件名: %s，准备开始上传", objectKey)
 
-		_, err = client.Object.Put(context.Background(), objectKey, bytes.NewReader(b), nil)
-		if err != nil {
-			logger.L.Errorf("上传到 COS 失败: %v", err)
-			return
+		result := pasteClipboard(cmd.Context(), client, settings)
+		switch {
+		case result.err != nil:
+			pasteFailed(ExitFailure, "%v", result.err)
+		case StructuredOutput():
+			printRecords([]record{result.record()})
+		case result.status == statusExists:
+			fmt.Printf("✅ 文件已存在，跳过上传: %s\n", result.link)
+			printSignedURL(result.signedURL, settings.presignExpires)
+		default:
+			fmt.Printf("✅ 上传成功: %s\n", result.link)
+			printSignedURL(result.signedURL, settings.presignExpires)
 		}
-		fmt.Printf("✅ 上传成功: %s/%s\n", bucketURL, objectKey)
-		logger.L.Debugf("成功上传文件: %s/%s，文件大小: %d 字节", bucketURL, objectKey, len(b))
+		settings.copyLinks([]string{result.link})
 	},
 }
 
+// pasteClipboard 上传剪切板中的图片
+func pasteClipboard(ctx context.Context, client *pkg.Client, settings *uploadSettings) uploadResult {
+	result := uploadResult{status: statusFailed}
+
+	b, fileExtension, isSVG, err := getClipboardContent()
+	if err != nil {
+		result.err = fmt.Errorf("读取剪切板失败: %v", err)
+		return result
+	}
+
+	logger.L.Debugf("准备上传 %s 文件，数据大小: %d 字节", func() string {
+		if isSVG {
+			return "SVG"
+		}
+		return "图片"
+	}(), len(b))
+
+	// 剪切板内容没有文件名，使用上传时间作为 {filename}
+	now := time.Now()
+	objectKey, err := renderObjectKey(client.Config, pkg.KeyData{
+		Filename: fmt.Sprintf("%s.%s", now.Format("2006-01-02-150405"), fileExtension),
+		Time:     now,
+		Content:  bytes.NewReader(b),
+		Size:     int64(len(b)),
+	}, settings.dedup)
+	if err != nil {
+		result.err = fmt.Errorf("生成文件名失败: %v", err)
+		return result
+	}
+	logger.L.Debugf("生成文件名: %s，准备开始上传", objectKey)
+
+	objectKey, skipped, err := client.UploadWithPolicy(ctx, objectKey, settings.policy, settings.fileOptions(contentTypeByExtension(fileExtension)), func(ctx context.Context, key string, opt *pkg.UploadOptions) (string, error) {
+		var err error
+		result.etag, err = client.Upload(ctx, key, bytes.NewReader(b), int64(len(b)), opt)
+		return key, err
+	})
+	result.key = objectKey
+	result.size = int64(len(b))
+	if err != nil {
+		result.err = fmt.Errorf("上传到 COS 失败: %v", err)
+		return result
+	}
+	result.url, result.signedURL, err = settings.objectURLs(ctx, client, objectKey)
+	if err == nil {
+		result.link, err = settings.formatter.Format(pkg.NewLink(result.url, objectKey, "", int64(len(b))))
+	}
+	if err != nil {
+		result.err = fmt.Errorf("上传成功，但%v", err)
+		return result
+	}
+	result.status = statusUploaded
+	if skipped {
+		result.status = statusExists
+	}
+	logger.L.Debugf("成功上传文件: %s，文件大小: %d 字节", result.url, len(b))
+	return result
+}
+
+// pasteFailed 输出错误信息并以 code 退出
+func pasteFailed(code int, format string, args ...any) {
+	if StructuredOutput() {
+		Fatalf(code, format, args...)
+	}
+	logger.L.Errorf(format, args...)
+	os.Exit(code)
+}
+
 func getClipboardContent() ([]byte, string, bool, error) {
 	textContent, textErr := clipboard.ReadAll()
 	if textErr == nil && len(textContent) > 0 {
 		logger.L.Debugf("读取到剪切板文本内容，长度: %d 字节", len(textContent))
 		logger.L.Debugf("剪切板内容预览: %s", previewText(textContent))
 		if isSVGContent(textContent) {
-			fmt.Println("✅ 检测到 SVG 格式内容")
+			fmt.Fprintln(messageOutput, "✅ 检测到 SVG 格式内容")
 			return []byte(textContent), "svg", true, nil
 		}
 		logger.L.Debug("SVG 检测失败，尝试按图片格式处理")
@@ -110,7 +173,7 @@ func getImageFromMacOS() ([]byte, string, bool, error) {
 }
 
 func getImageFromLinux() ([]byte, string, bool, error) {
-	fmt.Println("使用 Linux xclip 读取图片...")
+	fmt.Fprintln(messageOutput, "使用 Linux xclip 读取图片...")
 	logger.L.Debug("使用 Linux xclip 方式读取剪切板图片")
 	b, err := readImageFromClipboardLinux()
 	if err != nil {
@@ -121,7 +184,7 @@ func getImageFromLinux() ([]byte, string, bool, error) {
 }
 
 func getImageFromOther(textContent string, textErr error) ([]byte, string, bool, error) {
-	fmt.Println("使用通用文本方式读取...")
+	fmt.Fprintln(messageOutput, "使用通用文本方式读取...")
 	logger.L.Debug("使用通用文本方式处理剪切板内容")
 	if textErr != nil {
 		logger.L.Errorf("通用文本方式读取失败: %v", textErr)
@@ -129,11 +192,11 @@ func getImageFromOther(textContent string, textErr error) ([]byte, string, bool,
 	}
 	b, err := base64.StdEncoding.DecodeString(textContent)
 	if err != nil {
-		fmt.Println("内容不是 base64 格式，使用原始文本数据")
+		fmt.Fprintln(messageOutput, "内容不是 base64 格式，使用原始文本数据")
 		logger.L.Debug("内容不是 base64 格式，直接使用原始文本数据")
 		b = []byte(textContent)
 	} else {
-		fmt.Println("检测到 base64 格式内容")
+		fmt.Fprintln(messageOutput, "检测到 base64 格式内容")
 		logger.L.Debug("成功解析 base64 格式内容")
 	}
 	return checkImageType(b)
@@ -317,3 +380,8 @@ func getAppleScriptFormat(format string) string {
 		return "PNGf"
 	}
 }
+
+func init() {
+	SupportStructuredOutput(PasteCmd)
+	addUploadFlags(PasteCmd)
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	logger "github.com/bwangelme/cosp/log"
+	"github.com/bwangelme/cosp/pkg"
+)
+
+// isRemoteURL 判断参数是否为 http(s) 地址
+func isRemoteURL(arg string) bool {
+	lower := strings.ToLower(arg)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// uploadSources 按顺序上传本地文件和 http(s) 地址指向的远程图片，返回按 sources 顺序排列的结果
+//
+// 远程图片先下载到临时目录再上传，结果中的 path 为原始参数。
+func uploadSources(ctx context.Context, client *pkg.Client, sources []string, settings *uploadSettings) []uploadResult {
+	dir, err := os.MkdirTemp("", "cosp-remote-*")
+	if err != nil {
+		results := make([]uploadResult, len(sources))
+		for i, source := range sources {
+			results[i] = uploadResult{path: source, status: statusFailed, err: fmt.Errorf("创建临时目录失败: %v", err)}
+		}
+		return results
+	}
+	defer os.RemoveAll(dir)
+
+	tasks := make([]uploadTask, len(sources))
+	for i, source := range sources {
+		tasks[i] = uploadTask{path: source, explicit: true}
+		if isRemoteURL(source) {
+			// 每个地址使用单独的目录，避免文件名相同的图片互相覆盖
+			tasks[i].path, tasks[i].err = fetchRemote(ctx, client, source, filepath.Join(dir, strconv.Itoa(i)))
+		}
+	}
+	results := uploadAll(ctx, client, tasks, settings)
+	for i := range results {
+		results[i].path = sources[i]
+	}
+	return results
+}
+
+// fetchRemote 下载远程图片到 dir 中，返回本地文件路径
+//
+// 文件名取自地址的路径，用于生成 {filename}；没有扩展名时按内容补充。
+func fetchRemote(ctx context.Context, client *pkg.Client, rawURL, dir string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("地址格式不正确: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("地址格式不正确: %v", err)
+	}
+	httpClient := &http.Client{Timeout: time.Duration(client.Config.Timeout) * time.Second}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("下载远程图片失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("下载远程图片失败: %s", resp.Status)
+	}
+
+	target, err := saveTemp(resp.Body, dir, path.Base(u.Path))
+	if err != nil {
+		return "", err
+	}
+	logger.L.Debugf("下载远程图片 %s 到 %s", rawURL, target)
+	return target, nil
+}
+
+// saveTemp 将 r 的内容保存为 dir 中名为 name 的文件，返回文件路径
+//
+// 文件名用于生成 {filename}，为空时使用 image；没有扩展名时按内容识别并补充扩展名。
+func saveTemp(r io.Reader, dir, name string) (string, error) {
+	name = filepath.Base(name)
+	if name == "." || name == string(filepath.Separator) {
+		name = "image"
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("创建临时目录失败: %v", err)
+	}
+	target := filepath.Join(dir, name)
+	file, err := os.Create(target)
+	if err != nil {
+		return "", fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	defer file.Close()
+	if _, err := io.Copy(file, r); err != nil {
+		return "", fmt.Errorf("保存文件失败: %v", err)
+	}
+
+	if filepath.Ext(name) != "" {
+		return target, nil
+	}
+	head := make([]byte, sniffSize)
+	n, err := file.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("读取临时文件失败: %v", err)
+	}
+	detected, ok := sniffImage(head[:n])
+	if !ok {
+		return "", fmt.Errorf("文件内容不是图片")
+	}
+	renamed := target + extensionByType(detected)
+	if err := os.Rename(target, renamed); err != nil {
+		return "", fmt.Errorf("重命名临时文件失败: %v", err)
+	}
+	return renamed, nil
+}
@@ -0,0 +1,531 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	logger "github.com/bwangelme/cosp/log"
+	"github.com/bwangelme/cosp/pkg"
+
+	"github.com/spf13/cobra"
+	"github.com/tencentyun/cos-go-sdk-v5"
+)
+
+// defaultListen PicGo HTTP 服务默认监听的地址，编辑器插件默认连接这个地址
+const defaultListen = "127.0.0.1:36677"
+
+// maxRequestBody JSON 请求体的大小上限
+const maxRequestBody = 1 << 20
+
+var (
+	serveListen  string
+	serveToken   string
+	allowOrigins []string
+)
+
+var ServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "启动本地 HTTP 上传服务",
+	Long: `启动本地 HTTP 上传服务，浏览器扩展、编辑器插件等本地工具可以直接通过 HTTP 上传文件，
+不需要每次上传都启动一个进程。服务同时兼容 PicGo 的 HTTP 接口，可以作为 Obsidian、
+VS Code 等编辑器中 PicGo 插件的上传后端。
+
+接口:
+  POST   /api/upload        上传文件：multipart/form-data 上传表单中的所有文件，
+                            其他类型的请求体作为一个文件上传，文件名通过 ?name= 指定
+  POST   /api/paste         上传本机剪切板中的图片
+  GET    /api/files         列出文件，支持 ?prefix=、?marker=、?max_keys=
+  DELETE /api/files/<文件名>  删除文件
+  POST   /upload            PicGo 接口，Content-Type 必须为 application/json，请求体为
+                            {"list": ["文件路径或 http(s) 地址", ...]} 时按顺序上传这些文件，
+                            请求体为空时上传剪切板中的图片
+  POST   /heartbeat         PicGo 心跳接口
+
+/api 接口返回 {"records": [...]}，记录的字段与 --output json 相同，有文件失败时状态码为 500；
+请求错误时返回 {"error": "..."}。
+
+/api 接口需要带上 Authorization: Bearer <token>，token 使用 --token 或配置项 serve_token 设置，
+没有设置时启动时随机生成并输出，监听的不是本机地址时必须设置。设置了 token 时 PicGo 接口同样需要校验，也可以使用 ?key=<token>。
+
+为防止网页跨域调用本地服务，带有 Origin 请求头的浏览器请求只允许来自本机地址的页面，
+其他来源需要使用 --allow-origin 添加，如 --allow-origin app://obsidian.md。
+为防止 DNS 重绑定攻击，请求的 Host 只能是 IP 地址、localhost 或监听地址中的主机名。
+
+上传使用配置文件中的 key_template、dedup、on_conflict 和对象属性等配置。
+
+示例:
+  cosp serve                                  # 监听 127.0.0.1:36677
+  cosp serve --listen 127.0.0.1:8080          # 监听指定地址
+  cosp serve --token secret                   # 校验请求的 token
+  curl -H "Authorization: Bearer secret" -F file=@a.png http://127.0.0.1:36677/api/upload`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, _, err := pkg.NewClientWithFallback()
+		if err != nil {
+			Fatalf(ExitUsage, "创建COS客户端失败: %v", err)
+		}
+		settings, err := newUploadSettings(cmd, client.Config)
+		if err != nil {
+			Fatalf(ExitUsage, "%v", err)
+		}
+
+		token := serveToken
+		if token == "" {
+			token = client.Config.ServeToken
+		}
+		if err := checkServeToken(serveListen, token); err != nil {
+			Fatalf(ExitUsage, "%v", err)
+		}
+		apiToken := token
+		if token == "" {
+			if apiToken, err = randomToken(); err != nil {
+				Fatalf(ExitFailure, "%v", err)
+			}
+			fmt.Fprintf(messageOutput, "没有设置 token，/api 接口使用本次启动生成的 token: %s\n", apiToken)
+		}
+
+		listenHost, _, err := net.SplitHostPort(serveListen)
+		if err != nil {
+			Fatalf(ExitUsage, "--listen 无效: %v", err)
+		}
+		server := &http.Server{
+			Addr: serveListen,
+			Handler: (&uploadServer{
+				client:     client,
+				settings:   settings,
+				token:      token,
+				apiToken:   apiToken,
+				origins:    allowOrigins,
+				listenHost: listenHost,
+			}).routes(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			<-cmd.Context().Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(ctx)
+		}()
+
+		fmt.Fprintf(messageOutput, "上传服务已启动: http://%s，按 Ctrl-C 退出\n", serveListen)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			Fatalf(ExitFailure, "启动上传服务失败: %v", err)
+		}
+	},
+}
+
+// uploadServer 本地 HTTP 上传服务，所有请求共用同一个 COS 客户端
+type uploadServer struct {
+	client *pkg.Client
+	// settings 启动时确定的上传方式，每个请求通过 requestSettings 获取
+	settings *uploadSettings
+	// token 配置的 token，为空时 PicGo 接口不校验 token
+	token string
+	// apiToken /api 接口使用的 token，没有配置 token 时为启动时随机生成的 token
+	apiToken string
+	// origins 除本机地址外允许的浏览器请求来源
+	origins []string
+	// listenHost 监听地址中的主机名，请求的 Host 可以使用这个名字
+	listenHost string
+}
+
+// routes 返回上传服务的路由
+func (s *uploadServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/upload", s.api(s.handleUpload))
+	mux.HandleFunc("POST /api/paste", s.api(s.handlePaste))
+	mux.HandleFunc("GET /api/files", s.api(s.handleList))
+	mux.HandleFunc("DELETE /api/files/{key...}", s.api(s.handleDelete))
+	mux.HandleFunc("POST /upload", s.handlePicGoUpload)
+	mux.HandleFunc("POST /heartbeat", s.handleHeartbeat)
+	return s.checkHost(mux)
+}
+
+// checkHost 拒绝 Host 不是 IP 地址、localhost 或监听主机名的请求
+//
+// 恶意网页可以把自己的域名解析到 127.0.0.1（DNS 重绑定），以同源请求访问本地服务，
+// 这时请求的 Host 是恶意网页的域名。
+func (s *uploadServer) checkHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		host = strings.Trim(host, "[]")
+		if host != "localhost" && net.ParseIP(host) == nil && !strings.EqualFold(host, s.listenHost) {
+			logger.L.Warnf("%s %s: 拒绝 Host 为 %s 的请求", r.Method, r.URL.Path, r.Host)
+			writeJSON(w, http.StatusForbidden, apiError{Error: "Host 不正确"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorized 检查请求的 Authorization: Bearer <token>，没有该请求头时检查 ?key=<token>（PicGo 插件使用这种方式）。
+// token 为空时不校验
+func authorized(r *http.Request, token string) bool {
+	if token == "" {
+		return true
+	}
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		given = r.URL.Query().Get("key")
+	}
+	return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// allowedOrigin 检查浏览器请求的 Origin，防止任意网页跨域调用本地服务
+//
+// 没有 Origin 的请求来自命令行工具或编辑器插件，直接允许；浏览器请求只允许
+// 本机地址的页面和 --allow-origin 指定的来源。
+func (s *uploadServer) allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || slices.Contains(s.origins, origin) {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	return isLoopbackHost(u.Hostname())
+}
+
+// api 校验 /api 接口的请求来源和 token 并记录请求
+func (s *uploadServer) api(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.allowedOrigin(r) {
+			logger.L.Warnf("%s %s: 不允许来自 %s 的请求", r.Method, r.URL.Path, r.Header.Get("Origin"))
+			writeJSON(w, http.StatusForbidden, apiError{Error: "不允许跨域请求"})
+			return
+		}
+		if !authorized(r, s.apiToken) {
+			logger.L.Warnf("%s %s: token 不正确", r.Method, r.URL.Path)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="cosp"`)
+			writeJSON(w, http.StatusUnauthorized, apiError{Error: "token 不正确"})
+			return
+		}
+		logger.L.Infof("%s %s", r.Method, r.URL.Path)
+		next(w, r)
+	}
+}
+
+// requestSettings 返回单个请求使用的上传方式
+//
+// 服务会长时间运行，Expires 等相对时间需要按请求的时间重新计算，不能使用启动时的结果。
+func (s *uploadServer) requestSettings() (*uploadSettings, error) {
+	options, err := uploadOptions(s.client.Config)
+	if err != nil {
+		return nil, err
+	}
+	settings := *s.settings
+	settings.options = options
+	return &settings, nil
+}
+
+// apiResponse /api 接口的响应
+type apiResponse struct {
+	Records []record `json:"records"`
+	// IsTruncated 列出文件时是否还有更多文件，NextMarker 为获取下一页使用的 marker
+	IsTruncated bool   `json:"is_truncated,omitempty"`
+	NextMarker  string `json:"next_marker,omitempty"`
+}
+
+// apiError /api 接口请求错误时的响应
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// writeRecords 输出 /api 接口的记录，有记录失败时状态码为 500
+func writeRecords(w http.ResponseWriter, records []record) {
+	status := http.StatusOK
+	for _, rec := range records {
+		if rec.Status == recordFailed {
+			status = http.StatusInternalServerError
+		}
+	}
+	writeJSON(w, status, apiResponse{Records: records})
+}
+
+// handleUpload 上传请求中的文件
+//
+// multipart/form-data 请求上传表单中的所有文件，其他请求把请求体作为一个文件上传，
+// 文件名由 ?name= 指定，用于生成 {filename} 和确定扩展名，没有指定时按内容识别。
+func (s *uploadServer) handleUpload(w http.ResponseWriter, r *http.Request) {
+	settings, err := s.requestSettings()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: err.Error()})
+		return
+	}
+	dir, err := os.MkdirTemp("", "cosp-serve-*")
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: fmt.Sprintf("创建临时目录失败: %v", err)})
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		tasks []uploadTask
+		names []string
+	)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		reader, err := r.MultipartReader()
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, apiError{Error: fmt.Sprintf("请求格式不正确: %v", err)})
+			return
+		}
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, apiError{Error: fmt.Sprintf("读取请求失败: %v", err)})
+				return
+			}
+			// 跳过表单中的普通字段
+			if part.FileName() == "" {
+				continue
+			}
+			// 每个文件使用单独的目录，避免文件名相同的文件互相覆盖
+			path, err := saveTemp(part, filepath.Join(dir, strconv.Itoa(len(tasks))), part.FileName())
+			tasks = append(tasks, uploadTask{path: path, explicit: true, err: err})
+			names = append(names, part.FileName())
+		}
+		if len(tasks) == 0 {
+			writeJSON(w, http.StatusBadRequest, apiError{Error: "请求中没有文件"})
+			return
+		}
+	} else {
+		name := r.URL.Query().Get("name")
+		path, err := saveTemp(r.Body, dir, name)
+		tasks = []uploadTask{{path: path, explicit: true, err: err}}
+		names = []string{name}
+	}
+
+	results := uploadAll(r.Context(), s.client, tasks, settings)
+	records := make([]record, len(results))
+	for i, result := range results {
+		result.path = names[i]
+		records[i] = result.record()
+	}
+	writeRecords(w, records)
+}
+
+// handlePaste 上传本机剪切板中的图片
+func (s *uploadServer) handlePaste(w http.ResponseWriter, r *http.Request) {
+	settings, err := s.requestSettings()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: err.Error()})
+		return
+	}
+	result := pasteClipboard(r.Context(), s.client, settings)
+	writeRecords(w, []record{result.record()})
+}
+
+// handleList 列出文件，参数与 cosp list 相同，marker 只能是文件名
+func (s *uploadServer) handleList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opt := &cos.BucketGetOptions{
+		Prefix:  query.Get("prefix"),
+		Marker:  query.Get("marker"),
+		MaxKeys: 100,
+	}
+	if value := query.Get("max_keys"); value != "" {
+		maxKeys, err := strconv.Atoi(value)
+		if err != nil || maxKeys < 1 || maxKeys > 1000 {
+			writeJSON(w, http.StatusBadRequest, apiError{Error: "max_keys 无效，取值范围 1-1000"})
+			return
+		}
+		opt.MaxKeys = maxKeys
+	}
+
+	result, err := s.client.ListObjects(r.Context(), opt)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, apiError{Error: fmt.Sprintf("获取文件列表失败: %v", err)})
+		return
+	}
+	resp := apiResponse{Records: make([]record, 0, len(result.Contents)), IsTruncated: result.IsTruncated}
+	for _, obj := range result.Contents {
+		resp.Records = append(resp.Records, objectRecord(s.client.Config, obj))
+	}
+	if result.IsTruncated && len(result.Contents) > 0 {
+		resp.NextMarker = result.Contents[len(result.Contents)-1].Key
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleDelete 删除文件
+func (s *uploadServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	rec := record{Key: key, URL: s.client.Config.ObjectURL(key), Status: recordFailed}
+	resp, err := s.client.DeleteObject(r.Context(), key)
+	switch {
+	case err != nil:
+		rec.Error = err.Error()
+	case resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent:
+		rec.Error = fmt.Sprintf("状态码: %d", resp.StatusCode)
+	default:
+		rec.Status = recordDeleted
+	}
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	writeRecords(w, []record{rec})
+}
+
+// picgoResponse PicGo HTTP 接口的响应
+type picgoResponse struct {
+	Success bool   `json:"success"`
+	Result  any    `json:"result,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// picgoRequest PicGo HTTP 接口的上传请求
+type picgoRequest struct {
+	List []string `json:"list"`
+}
+
+// handlePicGoUpload 上传请求中的文件，没有文件时上传剪切板中的图片
+//
+// 与 PicGo 相同，上传失败时也返回 200，由 success 字段区分结果。
+func (s *uploadServer) handlePicGoUpload(w http.ResponseWriter, r *http.Request) {
+	if !s.allowedOrigin(r) {
+		logger.L.Warnf("%s %s: 不允许来自 %s 的请求", r.Method, r.URL.Path, r.Header.Get("Origin"))
+		writeJSON(w, http.StatusForbidden, picgoResponse{Message: "不允许跨域请求"})
+		return
+	}
+	// 网页可以不经过 CORS 预检跨域发送 text/plain 等类型的请求，只接受 JSON 请求
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeJSON(w, http.StatusUnsupportedMediaType, picgoResponse{Message: "Content-Type 必须为 application/json"})
+		return
+	}
+	if !authorized(r, s.token) {
+		logger.L.Warnf("%s %s: token 不正确", r.Method, r.URL.Path)
+		writeJSON(w, http.StatusUnauthorized, picgoResponse{Message: "token 不正确"})
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBody))
+	if err != nil {
+		writeJSON(w, http.StatusOK, picgoResponse{Message: fmt.Sprintf("读取请求失败: %v", err)})
+		return
+	}
+	var req picgoRequest
+	if len(strings.TrimSpace(string(body))) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			writeJSON(w, http.StatusOK, picgoResponse{Message: fmt.Sprintf("请求格式不正确: %v", err)})
+			return
+		}
+	}
+
+	settings, err := s.requestSettings()
+	if err != nil {
+		writeJSON(w, http.StatusOK, picgoResponse{Message: err.Error()})
+		return
+	}
+	var results []uploadResult
+	if len(req.List) == 0 {
+		logger.L.Infof("上传剪切板中的图片")
+		results = []uploadResult{pasteClipboard(r.Context(), s.client, settings)}
+	} else {
+		logger.L.Infof("上传 %d 个文件", len(req.List))
+		results = uploadSources(r.Context(), s.client, req.List, settings)
+	}
+
+	var (
+		urls     []string
+		failures []string
+	)
+	for _, result := range results {
+		if result.status != statusUploaded && result.status != statusExists {
+			message := errorString(result.err)
+			if result.path != "" {
+				message = result.path + ": " + message
+			}
+			failures = append(failures, message)
+			continue
+		}
+		urls = append(urls, result.accessURL())
+	}
+	if len(failures) > 0 {
+		logger.L.Errorf("上传失败: %s", strings.Join(failures, "; "))
+		writeJSON(w, http.StatusOK, picgoResponse{Message: strings.Join(failures, "; ")})
+		return
+	}
+	logger.L.Infof("上传成功: %s", strings.Join(urls, ", "))
+	writeJSON(w, http.StatusOK, picgoResponse{Success: true, Result: urls})
+}
+
+// handleHeartbeat 供插件检查服务是否可用
+func (s *uploadServer) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, picgoResponse{Success: true, Result: "alive"})
+}
+
+// writeJSON 以 JSON 格式输出响应
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	data, err := marshalJSON(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
+
+// checkServeToken 监听的不是本机地址时必须设置 token，否则其他机器可以不经校验调用 PicGo 接口
+func checkServeToken(listen, token string) error {
+	if token == "" && !isLoopback(listen) {
+		return fmt.Errorf("监听地址 %s 不是本机地址，需要使用 --token 或配置项 serve_token 设置 token", listen)
+	}
+	return nil
+}
+
+// randomToken 生成随机 token
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("生成 token 失败: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// isLoopback 判断监听地址是否只允许本机访问
+func isLoopback(listen string) bool {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return false
+	}
+	return isLoopbackHost(host)
+}
+
+// isLoopbackHost 判断主机名是否为 localhost 或本机 IP
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func init() {
+	ServeCmd.Flags().StringVar(&serveListen, "listen", defaultListen, "监听的地址")
+	ServeCmd.Flags().StringVar(&serveToken, "token", "", "校验请求使用的 Bearer token，默认使用配置项 serve_token，都没有设置时 /api 接口使用启动时随机生成的 token")
+	ServeCmd.Flags().StringArrayVar(&allowOrigins, "allow-origin", nil, "除本机地址外允许的浏览器请求来源，如 app://obsidian.md，可以指定多次")
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckHost(t *testing.T) {
+	s := &uploadServer{listenHost: "myhost.lan"}
+	handler := s.routes()
+
+	tests := []struct {
+		host string
+		want int
+	}{
+		{"127.0.0.1:36677", http.StatusOK},
+		{"localhost:36677", http.StatusOK},
+		{"localhost", http.StatusOK},
+		{"[::1]:36677", http.StatusOK},
+		{"192.168.1.10:36677", http.StatusOK},
+		{"myhost.lan:36677", http.StatusOK},
+		{"MYHOST.LAN", http.StatusOK},
+		{"evil.example.com:36677", http.StatusForbidden},
+		{"localhost.evil.example.com", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/heartbeat", nil)
+			r.Host = tt.host
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("Host %q: 状态码为 %d，期望 %d", tt.host, w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowedOrigin(t *testing.T) {
+	s := &uploadServer{origins: []string{"app://obsidian.md"}}
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"", true},
+		{"app://obsidian.md", true},
+		{"http://127.0.0.1:8080", true},
+		{"https://localhost", true},
+		{"http://[::1]:3000", true},
+		{"https://evil.example.com", false},
+		{"http://localhost.evil.example.com", false},
+		{"app://other.md", false},
+		{"file://localhost", false},
+		{"null", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/upload", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if got := s.allowedOrigin(r); got != tt.want {
+				t.Errorf("allowedOrigin(%q) = %v，期望 %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorized(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		query  string
+		want   bool
+	}{
+		{"没有设置 token", "", "", "", true},
+		{"Bearer token 正确", "secret", "Bearer secret", "", true},
+		{"Bearer token 错误", "secret", "Bearer wrong", "", false},
+		{"没有 token", "secret", "", "", false},
+		{"key 参数正确", "secret", "", "?key=secret", true},
+		{"key 参数错误", "secret", "", "?key=wrong", false},
+		{"不是 Bearer 认证", "secret", "Basic secret", "", false},
+		{"Bearer 优先于 key 参数", "secret", "Bearer wrong", "?key=secret", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/upload"+tt.query, nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			if got := authorized(r, tt.token); got != tt.want {
+				t.Errorf("authorized = %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIMiddleware(t *testing.T) {
+	s := &uploadServer{apiToken: "secret"}
+	handler := s.api(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name   string
+		origin string
+		header string
+		want   int
+	}{
+		{"token 正确", "", "Bearer secret", http.StatusNoContent},
+		{"本机页面", "http://localhost:8080", "Bearer secret", http.StatusNoContent},
+		{"没有 token", "", "", http.StatusUnauthorized},
+		{"token 错误", "", "Bearer wrong", http.StatusUnauthorized},
+		{"跨域请求", "https://evil.example.com", "Bearer secret", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/upload", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler(w, r)
+			if w.Code != tt.want {
+				t.Errorf("状态码为 %d，期望 %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestPicGoUploadToken(t *testing.T) {
+	s := &uploadServer{token: "secret"}
+
+	tests := []struct {
+		name        string
+		contentType string
+		query       string
+		want        int
+	}{
+		{"没有 token", "application/json", "", http.StatusUnauthorized},
+		{"key 参数错误", "application/json", "?key=wrong", http.StatusUnauthorized},
+		{"不是 JSON 请求", "text/plain", "?key=secret", http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/upload"+tt.query, nil)
+			r.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			s.handlePicGoUpload(w, r)
+			if w.Code != tt.want {
+				t.Errorf("状态码为 %d，期望 %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckServeToken(t *testing.T) {
+	tests := []struct {
+		listen  string
+		token   string
+		wantErr bool
+	}{
+		{"127.0.0.1:36677", "", false},
+		{"localhost:36677", "", false},
+		{"[::1]:36677", "", false},
+		{"0.0.0.0:36677", "", true},
+		{":36677", "", true},
+		{"192.168.1.10:36677", "", true},
+		{"myhost.lan:36677", "", true},
+		{"0.0.0.0:36677", "secret", false},
+		{"127.0.0.1:36677", "secret", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.listen+"/"+tt.token, func(t *testing.T) {
+			err := checkServeToken(tt.listen, tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkServeToken(%q, %q) = %v，期望出错: %v", tt.listen, tt.token, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bwangelme/cosp/pkg"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultPresignExpires 临时访问地址默认的有效期
+const defaultPresignExpires = time.Hour
+
+var (
+	signExpires time.Duration
+	signMethod  string
+)
+
+var SignCmd = &cobra.Command{
+	Use:   "sign <文件名>",
+	Short: "生成带签名的临时访问地址",
+	Long: `使用配置的密钥为文件生成带签名的临时地址，在有效期内不需要密钥即可访问私有文件。
+
+--method GET 生成下载地址，--method PUT 生成上传地址，可以交给没有密钥的人上传文件，
+如 curl -X PUT -T image.png '<地址>'。
+
+标准输出只包含生成的地址，可以直接在脚本中使用。地址使用 bucket 默认域名，
+使用临时密钥时，地址会在临时密钥过期后失效。
+
+示例:
+  cosp sign drafts/cover.png                        # 生成 1 小时内有效的下载地址
+  cosp sign drafts/cover.png --expires 24h          # 生成 24 小时内有效的下载地址
+  cosp sign uploads/report.pdf --method PUT         # 生成上传地址
+  cosp upload --presign draft.png                   # 上传后输出临时访问地址`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		method := strings.ToUpper(signMethod)
+		if method != http.MethodGet && method != http.MethodPut {
+			log.Fatalf("--method 无效: 可选值: GET, PUT")
+		}
+		if signExpires <= 0 {
+			log.Fatalf("--expires 无效: 有效期必须大于 0")
+		}
+
+		client, _, err := pkg.NewClientWithFallback()
+		if err != nil {
+			log.Fatalf("创建COS客户端失败: %v", err)
+		}
+		signed, err := client.PresignedURL(cmd.Context(), method, strings.TrimLeft(args[0], "/"), signExpires)
+		if err != nil {
+			log.Fatalf("生成临时地址失败: %v", err)
+		}
+		fmt.Println(signed)
+		fmt.Fprintf(os.Stderr, "%s内有效，到期时间: %s\n", formatDuration(signExpires), time.Now().Add(signExpires).Format("2006-01-02 15:04:05"))
+	},
+}
+
+// printSignedURL 输出私有文件 expires 内有效的临时访问地址，地址为空时不输出
+func printSignedURL(signed string, expires time.Duration) {
+	if signed == "" {
+		return
+	}
+	fmt.Printf("临时访问地址（%s内有效）: %s\n", formatDuration(expires), signed)
+}
+
+// formatDuration 将整天、整小时、整分钟的时长格式化为 1 天、2 小时、30 分钟
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%d 天", d/(24*time.Hour))
+	case d >= time.Hour && d%time.Hour == 0:
+		return fmt.Sprintf("%d 小时", d/time.Hour)
+	case d >= time.Minute && d%time.Minute == 0:
+		return fmt.Sprintf("%d 分钟", d/time.Minute)
+	}
+	return d.String()
+}
+
+func init() {
+	SignCmd.Flags().DurationVar(&signExpires, "expires", defaultPresignExpires, "地址的有效期，如 30m、24h")
+	SignCmd.Flags().StringVar(&signMethod, "method", http.MethodGet, "地址允许的请求方法: GET（下载）、PUT（上传）")
+}
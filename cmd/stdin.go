@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"time"
+
+	logger "github.com/bwangelme/cosp/log"
+	"github.com/bwangelme/cosp/pkg"
+
+	"github.com/h2non/filetype"
+)
+
+// uploadStdin 将标准输入的内容写入临时文件后上传
+func uploadStdin(ctx context.Context, client *pkg.Client, settings *uploadSettings) uploadResult {
+	result := uploadResult{path: "-", status: statusFailed}
+
+	tmp, err := os.CreateTemp("", "cosp-stdin-*")
+	if err != nil {
+		result.err = fmt.Errorf("创建临时文件失败: %v", err)
+		return result
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, os.Stdin)
+	if err != nil {
+		result.err = fmt.Errorf("读取标准输入失败: %v", err)
+		return result
+	}
+	if size == 0 {
+		result.err = fmt.Errorf("标准输入为空")
+		return result
+	}
+	logger.L.Debugf("从标准输入读取 %d 字节", size)
+
+	head := make([]byte, sniffSize)
+	n, err := tmp.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		result.err = fmt.Errorf("读取临时文件失败: %v", err)
+		return result
+	}
+	ext, detected, err := detectStdinType(head[:n])
+	if err != nil {
+		result.err = err
+		return result
+	}
+
+	now := time.Now()
+	filename := stdinName
+	if filename == "" {
+		// 与剪切板内容相同，使用上传时间作为 {filename}
+		filename = now.Format("2006-01-02-150405") + ext
+	}
+	objectKey, err := renderObjectKey(client.Config, pkg.KeyData{
+		Filename: filename,
+		Time:     now,
+		Content:  tmp,
+		Size:     size,
+	}, settings.dedup)
+	if err != nil {
+		result.err = fmt.Errorf("生成文件名失败: %v", err)
+		return result
+	}
+
+	objectKey, skipped, err := client.UploadWithPolicy(ctx, objectKey, settings.policy, settings.fileOptions(detected), func(ctx context.Context, key string, opt *pkg.UploadOptions) (string, error) {
+		var err error
+		result.etag, err = client.Upload(ctx, key, tmp, size, opt)
+		return key, err
+	})
+	result.key = objectKey
+	result.size = size
+	if err != nil {
+		result.err = fmt.Errorf("上传失败: %v", err)
+		return result
+	}
+	result.url, result.signedURL, err = settings.objectURLs(ctx, client, objectKey)
+	if err == nil {
+		result.link, err = settings.formatter.Format(pkg.NewLink(result.url, objectKey, stdinName, size))
+	}
+	if err != nil {
+		result.err = fmt.Errorf("上传成功，但%v", err)
+		return result
+	}
+	result.status = statusUploaded
+	if skipped {
+		result.status = statusExists
+	}
+	return result
+}
+
+// detectStdinType 确定标准输入内容的扩展名和 Content-Type
+//
+// 优先使用 --name 的扩展名和 --content-type，其次根据内容识别。
+// 没有指定 --name 和 --content-type 时只允许上传图片和 SVG。
+func detectStdinType(head []byte) (string, string, error) {
+	ext := filepath.Ext(stdinName)
+	detected := ""
+	if ext != "" {
+		detected = mime.TypeByExtension(ext)
+	}
+
+	isImage := false
+	if kind, _ := filetype.Match(head); kind != filetype.Unknown {
+		logger.L.Debugf("识别到标准输入的文件类型: %s", kind.MIME.Value)
+		isImage = filetype.IsImage(head)
+		if ext == "" {
+			ext = "." + kind.Extension
+		}
+		if detected == "" {
+			detected = kind.MIME.Value
+		}
+	} else if isSVGContent(string(head)) {
+		isImage = true
+		if ext == "" {
+			ext = ".svg"
+		}
+		if detected == "" {
+			detected = svgContentType
+		}
+	}
+
+	if ext == "" && contentType != "" {
+		ext = extensionByType(contentType)
+	}
+	if !isImage && stdinName == "" && contentType == "" {
+		return "", "", fmt.Errorf("标准输入的内容不是图片，只支持图片类型文件上传，可以使用 --name 或 --content-type 指定文件类型")
+	}
+	if ext == "" {
+		return "", "", fmt.Errorf("无法识别标准输入的文件类型，请使用 --name 指定带扩展名的文件名")
+	}
+	return ext, detected, nil
+}
@@ -4,56 +4,480 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"log"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
 	"time"
 
+	logger "github.com/bwangelme/cosp/log"
 	"github.com/bwangelme/cosp/pkg"
 
-	"github.com/h2non/filetype"
 	"github.com/spf13/cobra"
 )
 
+var (
+	resume    bool
+	noResume  bool
+	recursive bool
+	stdinName string
+	typora    bool
+)
+
 var UploadCmd = &cobra.Command{
-	Use:   "upload <filepath>",
+	Use:   "upload <filepath>...",
 	Short: "上传指定路径的图片到腾讯云 COS",
-	Args:  cobra.ExactArgs(1),
+	Long: `上传指定路径的图片到腾讯云 COS。
+
+可以同时上传多个文件、目录（需要 --recursive）或通配符匹配的文件，使用 max_thread
+个协程并发上传，最后输出每个文件的上传结果，有文件上传失败时以非 0 状态码退出。
+目录和通配符中的非图片文件以及隐藏文件会被跳过。
+
+文件路径为 - 时从标准输入读取内容，根据内容识别文件类型。无法识别时可以使用 --name
+指定文件名（按扩展名确定类型）或使用 --content-type 指定类型。
+
+大文件会自动使用分块上传，默认开启断点续传：上传中断后再次上传同一文件，
+只会上传尚未完成的分块。可以使用 cosp multipart 命令查看和清理未完成的分块上传。
+
+文件名默认为上传时间，如 2024-01-15-143022.png，可以通过配置项 key_template
+或 --key-template 参数修改，也可以使用 --key 直接指定。
+
+COS 中已存在同名文件时默认在文件名后追加 -1、-2 等后缀，可以通过配置项 on_conflict
+或 --on-conflict 参数修改为 fail（报错）、overwrite（覆盖）或 skip（跳过）。
+
+使用 --dedup 或配置 dedup = true 时按文件内容生成文件名，COS 中已经存在相同内容的
+文件时跳过上传，直接输出已有文件的地址。
+
+使用 --typora 时可以作为 Typora 等编辑器的自定义上传命令：参数可以是本地文件或 http(s)
+地址（先下载再上传），全部上传成功后按参数顺序每行输出一个文件地址，标准输出中没有其他内容。
+
+上传时根据文件内容设置 Content-Type，Cache-Control、Expires、存储类型和自定义元数据
+可以通过参数或配置项 cache_control、expires、storage_class、meta 等设置。
+
+示例:
+  cosp upload image.jpg                                         # 上传本地图片文件
+  cosp upload a.png b.png "diagrams/*.svg"                      # 上传多个文件
+  cosp upload -r ./screenshots                                  # 上传目录中的所有图片
+  plot.py | cosp upload -                                       # 上传标准输入中的图片
+  cat chart.svg | cosp upload --name chart.svg -                # 指定标准输入的文件名
+  cosp upload --no-resume image.psd                             # 不使用断点续传，重新上传
+  cosp upload --key-template "{year}/{month}/{md5:8}{ext}" a.png  # 按模板生成文件名
+  cosp upload --key avatar/me.png me.png                        # 指定文件名
+  cosp upload --dedup image.jpg                                 # 相同内容的文件只上传一次
+  cosp upload --key logo.png --on-conflict overwrite logo.png   # 覆盖已有文件
+  cosp upload --cache-control max-age=31536000 --meta post=hello a.png  # 设置对象属性
+  cosp upload --copy --format markdown a.png                    # 将 Markdown 链接复制到剪切板
+  cosp upload --typora a.png https://example.com/b.png          # 供 Typora 调用，每行输出一个地址`,
+	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		filePath := args[0]
-		file, err := os.Open(filePath)
-		if err != nil {
-			log.Fatalf("无法打开文件: %v", err)
+		if typora {
+			uploadForEditor(cmd, args)
+			return
 		}
-		defer file.Close()
-
-		buf := make([]byte, 261)
-		_, err = file.Read(buf)
-		if err != nil && err != io.EOF {
-			log.Fatalf("读取文件失败: %v", err)
+		if len(args) > 1 && slices.Contains(args, "-") {
+			Fatalf(ExitUsage, "- 表示从标准输入读取，不能与其他文件一起上传")
+		}
+		if stdinName != "" && args[0] != "-" {
+			Fatalf(ExitUsage, "--name 只能在从标准输入读取时使用")
 		}
-		if !filetype.IsImage(buf) {
-			log.Fatalf("只支持图片类型文件上传")
+		tasks := collectUploadTasks(args)
+		if exactKey != "" && len(tasks) > 1 {
+			Fatalf(ExitUsage, "--key 只能在上传单个文件时使用")
 		}
-		file.Seek(0, io.SeekStart)
 
 		// 使用新的客户端初始化方式
-		client, bucketURL, err := pkg.NewClientWithFallback()
+		client, _, err := pkg.NewClientWithFallback()
+		if err != nil {
+			Fatalf(ExitUsage, "创建COS客户端失败: %v", err)
+		}
+		settings, err := newUploadSettings(cmd, client.Config)
 		if err != nil {
-			log.Fatalf("创建COS客户端失败: %v", err)
+			Fatalf(ExitUsage, "%v", err)
 		}
 
-		// 获取原文件的扩展名
-		originalExt := filepath.Ext(filePath)
+		var results []uploadResult
+		if len(tasks) == 1 && tasks[0].path == "-" {
+			results = []uploadResult{uploadStdin(cmd.Context(), client, settings)}
+		} else {
+			results = uploadAll(cmd.Context(), client, tasks, settings)
+		}
 
-		// 使用时间戳格式生成新的文件名，保留原文件的扩展名
-		timestamp := time.Now().Format("2006-01-02-150405")
-		objectKey := timestamp + originalExt
+		switch {
+		case StructuredOutput():
+			records := make([]record, 0, len(results))
+			for _, result := range results {
+				records = append(records, result.record())
+			}
+			printRecords(records)
+		case len(tasks) == 1 && tasks[0].explicit:
+			// 只上传一个文件时保持简洁的输出
+			result := results[0]
+			switch result.status {
+			case statusUploaded:
+				fmt.Printf("上传成功: %s\n", result.link)
+			case statusExists:
+				fmt.Printf("文件已存在，跳过上传: %s\n", result.link)
+			default:
+				Fatalf(ExitFailure, "%v", result.err)
+			}
+			printSignedURL(result.signedURL, settings.presignExpires)
+		default:
+			printUploadResults(results)
+		}
 
-		_, err = client.Object.Put(context.Background(), objectKey, file, nil)
-		if err != nil {
-			log.Fatalf("上传失败: %v", err)
+		var (
+			links  []string
+			failed bool
+		)
+		for _, result := range results {
+			switch result.status {
+			case statusUploaded, statusExists:
+				links = append(links, result.link)
+			case statusFailed:
+				failed = true
+			}
+		}
+		settings.copyLinks(links)
+		if failed {
+			os.Exit(ExitFailure)
 		}
-		fmt.Printf("上传成功: %s/%s\n", bucketURL, objectKey)
 	},
 }
+
+// uploadForEditor 供 Typora 等编辑器调用的上传方式
+//
+// 参数可以是本地文件或 http(s) 地址，全部上传成功后按参数顺序每行输出一个文件地址，
+// 标准输出中没有其他内容；有文件上传失败时在标准错误输出原因，并以非 0 状态码退出。
+func uploadForEditor(cmd *cobra.Command, args []string) {
+	if StructuredOutput() {
+		Fatalf(ExitUsage, "--typora 不能与 --output 一起使用")
+	}
+	if slices.Contains(args, "-") {
+		Fatalf(ExitUsage, "--typora 不支持从标准输入读取")
+	}
+	redirectMessages()
+
+	client, _, err := pkg.NewClientWithFallback()
+	if err != nil {
+		Fatalf(ExitUsage, "创建COS客户端失败: %v", err)
+	}
+	settings, err := newUploadSettings(cmd, client.Config)
+	if err != nil {
+		Fatalf(ExitUsage, "%v", err)
+	}
+
+	results := uploadSources(cmd.Context(), client, args, settings)
+	failed := false
+	for _, result := range results {
+		if result.status != statusUploaded && result.status != statusExists {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", result.path, result.err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(ExitFailure)
+	}
+	links := make([]string, 0, len(results))
+	for _, result := range results {
+		fmt.Println(result.accessURL())
+		links = append(links, result.link)
+	}
+	settings.copyLinks(links)
+}
+
+// 单个文件的上传状态
+const (
+	statusUploaded = "成功"
+	statusExists   = "已存在"
+	statusSkipped  = "跳过"
+	statusFailed   = "失败"
+)
+
+// uploadTask 待上传的本地文件
+type uploadTask struct {
+	path string
+	// explicit 文件是否直接在命令行中指定，目录和通配符匹配到的非图片文件会被跳过，而不是报错
+	explicit bool
+	// err 无法上传的原因，如文件不存在、未指定 --recursive 的目录
+	err error
+}
+
+// uploadResult 单个文件的上传结果
+type uploadResult struct {
+	path string
+	key  string
+	url  string
+	// link 按 --format 格式化后的文件地址
+	link string
+	// signedURL 私有文件的临时访问地址
+	signedURL string
+	size      int64
+	// etag 上传后对象的 ETag，跳过上传时为空
+	etag   string
+	status string
+	err    error
+}
+
+// accessURL 返回可以直接访问的地址，私有文件返回临时访问地址
+func (r uploadResult) accessURL() string {
+	if r.signedURL != "" {
+		return r.signedURL
+	}
+	return r.url
+}
+
+// record 转换为结构化输出的记录
+func (r uploadResult) record() record {
+	return record{
+		Key:       r.key,
+		URL:       r.url,
+		Size:      r.size,
+		ETag:      r.etag,
+		Status:    recordStatus(r.status),
+		Error:     errorString(r.err),
+		Path:      r.path,
+		SignedURL: r.signedURL,
+	}
+}
+
+// collectUploadTasks 按参数顺序展开命令行参数中的目录和通配符，返回待上传的文件
+func collectUploadTasks(args []string) []uploadTask {
+	var (
+		tasks []uploadTask
+		seen  = map[string]bool{}
+	)
+	add := func(path string, explicit bool) {
+		if seen[path] {
+			return
+		}
+		seen[path] = true
+		tasks = append(tasks, uploadTask{path: path, explicit: explicit})
+	}
+	fail := func(path string, err error) {
+		tasks = append(tasks, uploadTask{path: path, explicit: true, err: err})
+	}
+
+	for _, arg := range args {
+		if arg == "-" {
+			add(arg, true)
+			continue
+		}
+		paths := []string{arg}
+		// 文件名本身可能包含 *、?、[，如 shot[1].png，文件存在时不作为通配符
+		_, statErr := os.Stat(arg)
+		isGlob := statErr != nil && strings.ContainsAny(arg, "*?[")
+		if isGlob {
+			matches, err := filepath.Glob(arg)
+			if err != nil {
+				fail(arg, fmt.Errorf("通配符格式不正确: %v", err))
+				continue
+			}
+			if len(matches) == 0 {
+				fail(arg, fmt.Errorf("没有匹配的文件"))
+				continue
+			}
+			paths = matches
+		}
+
+		for _, path := range paths {
+			stat, err := os.Stat(path)
+			if err != nil {
+				fail(path, fmt.Errorf("无法打开文件: %v", err))
+				continue
+			}
+			if !stat.IsDir() {
+				add(path, !isGlob)
+				continue
+			}
+			if !recursive {
+				// 通配符匹配到的目录直接忽略
+				if !isGlob {
+					fail(path, fmt.Errorf("%s 是目录，使用 --recursive 上传目录中的文件", path))
+				}
+				continue
+			}
+			err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				// 跳过 .git、.DS_Store 等隐藏文件和目录
+				if p != path && strings.HasPrefix(d.Name(), ".") {
+					if d.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if d.Type().IsRegular() {
+					add(p, false)
+				}
+				return nil
+			})
+			if err != nil {
+				fail(path, fmt.Errorf("遍历目录失败: %v", err))
+			}
+		}
+	}
+	return tasks
+}
+
+// uploadAll 使用 max_thread 个协程并发上传文件，返回按 tasks 顺序排列的结果
+func uploadAll(ctx context.Context, client *pkg.Client, tasks []uploadTask, settings *uploadSettings) []uploadResult {
+	results := make([]uploadResult, len(tasks))
+	ch := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < client.Config.MaxThread && i < len(tasks); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range ch {
+				results[idx] = uploadOne(ctx, client, tasks[idx], settings)
+				logger.L.Debugf("%s: %s %s", tasks[idx].path, results[idx].status, results[idx].key)
+			}
+		}()
+	}
+	for idx := range tasks {
+		ch <- idx
+	}
+	close(ch)
+	wg.Wait()
+	return results
+}
+
+// uploadOne 上传单个文件
+func uploadOne(ctx context.Context, client *pkg.Client, task uploadTask, settings *uploadSettings) uploadResult {
+	result := uploadResult{path: task.path, status: statusFailed}
+	if task.err != nil {
+		result.err = task.err
+		return result
+	}
+	if ctx.Err() != nil {
+		result.err = fmt.Errorf("已取消")
+		return result
+	}
+
+	file, err := os.Open(task.path)
+	if err != nil {
+		result.err = fmt.Errorf("无法打开文件: %v", err)
+		return result
+	}
+	defer file.Close()
+
+	head := make([]byte, sniffSize)
+	n, err := file.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		result.err = fmt.Errorf("读取文件失败: %v", err)
+		return result
+	}
+	detected, ok := sniffImage(head[:n])
+	if !ok {
+		if !task.explicit {
+			result.status = statusSkipped
+			result.err = fmt.Errorf("不是图片")
+			return result
+		}
+		result.err = fmt.Errorf("只支持图片类型文件上传")
+		return result
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		result.err = fmt.Errorf("获取文件信息失败: %v", err)
+		return result
+	}
+	objectKey, err := renderObjectKey(client.Config, pkg.KeyData{
+		Filename: filepath.Base(task.path),
+		Time:     time.Now(),
+		Content:  file,
+		Size:     stat.Size(),
+	}, settings.dedup)
+	if err != nil {
+		result.err = fmt.Errorf("生成文件名失败: %v", err)
+		return result
+	}
+
+	objectKey, skipped, err := client.UploadWithPolicy(ctx, objectKey, settings.policy, settings.fileOptions(detected), func(ctx context.Context, key string, opt *pkg.UploadOptions) (string, error) {
+		var err error
+		key, result.etag, err = client.UploadFile(ctx, key, file, resume && !noResume, opt)
+		return key, err
+	})
+	result.key = objectKey
+	result.size = stat.Size()
+	if err != nil {
+		result.err = fmt.Errorf("上传失败: %v", err)
+		return result
+	}
+	result.url, result.signedURL, err = settings.objectURLs(ctx, client, objectKey)
+	if err == nil {
+		result.link, err = settings.formatter.Format(pkg.NewLink(result.url, objectKey, filepath.Base(task.path), stat.Size()))
+	}
+	if err != nil {
+		result.err = fmt.Errorf("上传成功，但%v", err)
+		return result
+	}
+	result.status = statusUploaded
+	if skipped {
+		result.status = statusExists
+	}
+	return result
+}
+
+// printUploadResults 输出每个文件的上传结果和汇总信息
+func printUploadResults(results []uploadResult) {
+	counts := map[string]int{}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "文件\t状态\t文件地址")
+	fmt.Fprintln(w, "----\t----\t----")
+	for _, result := range results {
+		counts[result.status]++
+		detail := result.link
+		if result.signedURL != "" {
+			detail = result.signedURL
+		}
+		if result.err != nil {
+			detail = result.err.Error()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", result.path, result.status, detail)
+	}
+	w.Flush()
+
+	fmt.Printf("\n共 %d 个文件，成功 %d 个，已存在 %d 个，跳过 %d 个，失败 %d 个\n",
+		len(results), counts[statusUploaded], counts[statusExists], counts[statusSkipped], counts[statusFailed])
+}
+
+// renderObjectKey 生成对象 key，优先级: --key > --key-template > 配置项 key_template
+//
+// 去重模式下 key 必须只由文件内容决定: --key-template 不满足时报错，
+// 配置项 key_template 不满足时使用 pkg.DefaultDedupKeyTemplate。
+func renderObjectKey(config *pkg.COSConfig, data pkg.KeyData, dedup bool) (string, error) {
+	if exactKey != "" {
+		return strings.TrimLeft(exactKey, "/"), nil
+	}
+	template := config.KeyTemplate
+	if keyTemplate != "" {
+		template = keyTemplate
+	}
+	if dedup && !pkg.IsContentAddressed(template) {
+		if keyTemplate != "" {
+			return "", fmt.Errorf("去重模式下 key 模板只能包含 {md5}、{sha256} 和 {ext}: %s", keyTemplate)
+		}
+		logger.L.Debugf("key_template %s 不是由文件内容决定的，去重模式使用 %s", template, pkg.DefaultDedupKeyTemplate)
+		template = pkg.DefaultDedupKeyTemplate
+	}
+	return pkg.RenderKey(template, data)
+}
+
+func init() {
+	SupportStructuredOutput(UploadCmd)
+	UploadCmd.Flags().BoolVar(&resume, "resume", true, "大文件上传中断后从断点继续上传")
+	UploadCmd.Flags().BoolVar(&noResume, "no-resume", false, "不使用断点续传，重新上传整个文件")
+	UploadCmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "上传目录中的所有图片，包括子目录")
+	UploadCmd.Flags().StringVar(&stdinName, "name", "", "从标准输入读取时使用的文件名，用于确定扩展名和 {filename}")
+	UploadCmd.Flags().BoolVar(&typora, "typora", false, "供 Typora 等编辑器调用：参数可以是本地文件或 http(s) 地址，按顺序每行只输出一个文件地址")
+	addUploadFlags(UploadCmd)
+	UploadCmd.MarkFlagsMutuallyExclusive("typora", "recursive")
+	UploadCmd.MarkFlagsMutuallyExclusive("typora", "name")
+	UploadCmd.MarkFlagsMutuallyExclusive("typora", "key")
+}
@@ -5,8 +5,10 @@ go 1.24.4
 require (
 	github.com/atotto/clipboard v0.1.4
 	github.com/h2non/filetype v1.1.3
+	github.com/sirupsen/logrus v1.9.3
 	github.com/spf13/cobra v1.9.1
 	github.com/tencentyun/cos-go-sdk-v5 v0.7.66
+	golang.org/x/term v0.30.0
 	gopkg.in/ini.v1 v1.67.0
 )
 
@@ -16,7 +18,6 @@ require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/mitchellh/mapstructure v1.4.3 // indirect
 	github.com/mozillazg/go-httpheader v0.2.1 // indirect
-	github.com/sirupsen/logrus v1.9.3 // indirect
 	github.com/spf13/pflag v1.0.6 // indirect
-	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+	golang.org/x/sys v0.31.0 // indirect
 )
@@ -1,16 +1,25 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/bwangelme/cosp/cmd"
 	logger "github.com/bwangelme/cosp/log"
+	"github.com/bwangelme/cosp/pkg"
 
 	"github.com/spf13/cobra"
 )
 
-var debugMode bool
+var (
+	debugMode  bool
+	profile    string
+	configFile string
+	output     string
+)
 
 func main() {
 	var rootCmd = &cobra.Command{
@@ -22,15 +31,20 @@ func main() {
 支持文件上传、剪切板图片上传、文件列表查看和删除等功能。
 
 使用前请先配置您的腾讯云 COS 凭证：
-  1. 在用户主目录下创建 .cos.conf 文件
-  2. 参考 example.cos.conf 文件配置相关参数
+  1. 运行 cosp config init 创建配置文件，或手动创建 ~/.cos.conf（可使用 --config 指定配置文件）
+  2. 参考 example.cos.conf 文件配置相关参数，使用 cosp config validate 检查配置
+  3. 可以在配置文件中添加多个配置节，使用 --profile 切换
+  4. 也可以通过 COS_SECRET_ID、COS_SECRET_KEY 等环境变量提供配置
 
 示例:
   cosp upload image.jpg    # 上传本地图片文件
   cosp paste              # 上传剪切板中的图片
   cosp list               # 列出 COS 中的文件
   cosp delete file.jpg    # 删除指定文件`,
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		PersistentPreRun: func(c *cobra.Command, args []string) {
+			cmd.CheckStructuredOutput(c)
+			pkg.SetProfile(profile)
+			pkg.SetConfigFile(configFile)
 			if debugMode {
 				logger.SetDebugLevel()
 				logger.L.Debug("已启用调试模式")
@@ -40,6 +54,21 @@ func main() {
 
 	// 添加全局 debug 选项
 	rootCmd.PersistentFlags().BoolVarP(&debugMode, "debug", "d", false, "启用调试模式，显示详细的调试信息")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "配置文件路径，默认读取 COSP_CONFIG 环境变量、~/.cos.conf 或 $XDG_CONFIG_HOME/cosp/config")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "使用配置文件中指定的 profile（配置节），默认读取 COSP_PROFILE 环境变量或 default_profile")
+	rootCmd.PersistentFlags().StringVar(&output, "output", "", "输出格式: json, jsonl, yaml，默认输出人类可读的文本")
+
+	// 在检查命令参数之前设置输出格式，参数错误也能以 JSON 输出
+	cobra.OnInitialize(func() {
+		if err := cmd.SetOutputFormat(output); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(cmd.ExitUsage)
+		}
+		if cmd.StructuredOutput() {
+			rootCmd.SilenceErrors = true
+			rootCmd.SilenceUsage = true
+		}
+	})
 
 	// 添加版本命令
 	var versionCmd = &cobra.Command{
@@ -51,14 +80,33 @@ func main() {
 		},
 	}
 
+	cmd.SupportStructuredOutput(versionCmd)
+
 	rootCmd.AddCommand(cmd.PasteCmd)
 	rootCmd.AddCommand(cmd.UploadCmd)
 	rootCmd.AddCommand(cmd.ListCmd)
 	rootCmd.AddCommand(cmd.DeleteCmd)
+	rootCmd.AddCommand(cmd.DownloadCmd)
+	rootCmd.AddCommand(cmd.MultipartCmd)
+	rootCmd.AddCommand(cmd.ConfigCmd)
+	rootCmd.AddCommand(cmd.ACLCmd)
+	rootCmd.AddCommand(cmd.SignCmd)
+	rootCmd.AddCommand(cmd.ServeCmd)
 	rootCmd.AddCommand(versionCmd)
 
-	if err := rootCmd.Execute(); err != nil {
+	// 收到 Ctrl-C 后取消正在进行的请求，让命令有机会保存进度并清理；
+	// 再次按下 Ctrl-C 时直接退出
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		if cmd.StructuredOutput() {
+			cmd.Fatalf(cmd.ExitUsage, "%v", err)
+		}
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(cmd.ExitUsage)
 	}
 }
@@ -0,0 +1,74 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tencentyun/cos-go-sdk-v5"
+)
+
+// 对象的访问权限，对应 --acl 参数
+const (
+	// ACLPrivate 只有 bucket 所有者和授权用户可以访问
+	ACLPrivate = "private"
+	// ACLPublicRead 所有人都可以读取
+	ACLPublicRead = "public-read"
+	// ACLDefault 继承 bucket 的访问权限
+	ACLDefault = "default"
+)
+
+// allUsersURI 表示所有用户的授权对象
+const allUsersURI = "http://cam.qcloud.com/groups/global/AllUsers"
+
+// acls 支持设置的访问权限
+var acls = []string{ACLPrivate, ACLPublicRead, ACLDefault}
+
+// ParseACL 检查访问权限，不区分大小写
+func ParseACL(value string) (string, error) {
+	acl := strings.ToLower(value)
+	for _, a := range acls {
+		if acl == a {
+			return acl, nil
+		}
+	}
+	return "", fmt.Errorf("可选值: %s", strings.Join(acls, ", "))
+}
+
+// IsPublicRead 判断对象的 ACL 是否授予了所有人读权限
+//
+// COS 不区分 private 和 default：两者的授权列表中都只有所有者，
+// default 的对象能否公开访问取决于 bucket 的访问权限。
+func IsPublicRead(acl *cos.ObjectGetACLResult) bool {
+	for _, grant := range acl.AccessControlList {
+		if grant.Grantee == nil || grant.Grantee.URI != allUsersURI {
+			continue
+		}
+		if grant.Permission == "READ" || grant.Permission == "FULL_CONTROL" {
+			return true
+		}
+	}
+	return false
+}
+
+// GetObjectACL 获取对象的访问权限
+func (c *Client) GetObjectACL(ctx context.Context, key string) (*cos.ObjectGetACLResult, error) {
+	var result *cos.ObjectGetACLResult
+	_, err := c.retry(ctx, "获取文件权限", func(ctx context.Context) (resp *cos.Response, err error) {
+		result, resp, err = c.Object.GetACL(ctx, key)
+		return resp, err
+	})
+	return result, err
+}
+
+// SetObjectACL 修改对象的访问权限，acl 为 private、public-read 或 default
+func (c *Client) SetObjectACL(ctx context.Context, key, acl string) error {
+	if err := c.CheckWritable(); err != nil {
+		return err
+	}
+	opt := &cos.ObjectPutACLOptions{Header: &cos.ACLHeaderOptions{XCosACL: acl}}
+	_, err := c.retry(ctx, "修改文件权限", func(ctx context.Context) (*cos.Response, error) {
+		return c.Object.PutACL(ctx, key, opt)
+	})
+	return err
+}
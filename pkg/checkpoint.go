@@ -0,0 +1,173 @@
+package pkg
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Checkpoint 记录分块上传的断点信息，用于中断后继续上传
+type Checkpoint struct {
+	FilePath string         `json:"file_path"`
+	Size     int64          `json:"size"`
+	ModTime  int64          `json:"mod_time"`
+	Bucket   string         `json:"bucket"`
+	Key      string         `json:"key"`
+	UploadID string         `json:"upload_id"`
+	PartSize int64          `json:"part_size"`
+	Parts    map[int]string `json:"parts"`
+	// Options 初始化分块上传时使用的上传参数的摘要，参数改变后不能继续使用这次分块上传
+	Options string `json:"options"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// checkpointDir 返回断点文件所在目录
+func checkpointDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("无法获取缓存目录: %v", err)
+	}
+	return filepath.Join(cacheDir, "cosp", "checkpoints"), nil
+}
+
+// checkpointPath 根据文件路径、大小和修改时间计算断点文件路径
+func checkpointPath(filePath string, size, modTime int64) (string, error) {
+	dir, err := checkpointDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s|%d|%d", filePath, size, modTime)))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json"), nil
+}
+
+// NewCheckpoint 为本地文件创建断点记录
+func NewCheckpoint(file *os.File) (*Checkpoint, error) {
+	filePath, err := filepath.Abs(file.Name())
+	if err != nil {
+		return nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	path, err := checkpointPath(filePath, stat.Size(), stat.ModTime().UnixNano())
+	if err != nil {
+		return nil, err
+	}
+	return &Checkpoint{
+		FilePath: filePath,
+		Size:     stat.Size(),
+		ModTime:  stat.ModTime().UnixNano(),
+		Parts:    map[int]string{},
+		path:     path,
+	}, nil
+}
+
+// Load 从磁盘读取断点记录，文件不存在时返回 false
+func (cp *Checkpoint) Load() (bool, error) {
+	data, err := os.ReadFile(cp.path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return false, fmt.Errorf("解析断点文件失败: %v", err)
+	}
+	if cp.Parts == nil {
+		cp.Parts = map[int]string{}
+	}
+	return true, nil
+}
+
+// SetPart 记录一个已上传完成的分块并写入磁盘
+func (cp *Checkpoint) SetPart(partNumber int, etag string) error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.Parts[partNumber] = etag
+	return cp.save()
+}
+
+// Save 将断点记录写入磁盘
+func (cp *Checkpoint) Save() error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return cp.save()
+}
+
+func (cp *Checkpoint) save() error {
+	if err := os.MkdirAll(filepath.Dir(cp.path), 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	// 先写临时文件再重命名，避免中断时留下不完整的断点文件
+	tmpPath := cp.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, cp.path)
+}
+
+// Remove 删除断点文件
+func (cp *Checkpoint) Remove() error {
+	err := os.Remove(cp.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// ListCheckpoints 列出本地保存的所有断点记录
+func ListCheckpoints() ([]*Checkpoint, error) {
+	dir, err := checkpointDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoints []*Checkpoint
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		cp := &Checkpoint{path: filepath.Join(dir, entry.Name())}
+		if ok, err := cp.Load(); err != nil || !ok {
+			continue
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+	return checkpoints, nil
+}
+
+// RemoveCheckpointsByUploadID 删除指定 UploadId 对应的本地断点记录
+func RemoveCheckpointsByUploadID(uploadID string) error {
+	checkpoints, err := ListCheckpoints()
+	if err != nil {
+		return err
+	}
+	for _, cp := range checkpoints {
+		if cp.UploadID == uploadID {
+			if err := cp.Remove(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
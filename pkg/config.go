@@ -0,0 +1,658 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+// DefaultProfile 默认使用的配置节
+const DefaultProfile = "common"
+
+// configKeys 支持的配置项，每一项都可以通过 COS_ 前缀的大写环境变量覆盖，如 COS_SECRET_ID
+var configKeys = []string{
+	"secret_id",
+	"secret_key",
+	"session_token",
+	"credential_process",
+	"bucket",
+	"region",
+	"max_thread",
+	"part_size",
+	"retry",
+	"timeout",
+	"schema",
+	"verify",
+	"anonymous",
+	"url_base",
+	"key_template",
+	"dedup",
+	"on_conflict",
+	"cache_control",
+	"content_disposition",
+	"expires",
+	"storage_class",
+	"meta",
+	"default_format",
+	"format_template",
+	"copy_result",
+	"serve_token",
+}
+
+var (
+	// profile 通过 --profile 参数指定的配置节
+	profile string
+	// configFile 通过 --config 参数指定的配置文件
+	configFile string
+)
+
+// SetProfile 设置要使用的配置节，为空时依次使用 COSP_PROFILE 环境变量、
+// 配置文件中的 default_profile 和 [common]
+func SetProfile(name string) {
+	profile = name
+}
+
+// SetConfigFile 设置配置文件路径，为空时依次使用 COSP_CONFIG 环境变量、
+// ~/.cos.conf 和 $XDG_CONFIG_HOME/cosp/config
+func SetConfigFile(path string) {
+	configFile = path
+}
+
+// COSConfig 配置结构体
+type COSConfig struct {
+	// ConfigFile 读取的配置文件路径，没有使用配置文件时为空
+	ConfigFile string
+	Profile    string
+	SecretID   string
+	SecretKey  string
+	// SessionToken 使用 STS 临时密钥时的 token
+	SessionToken string
+	// CredentialProcess 获取密钥的外部命令，设置后忽略 SecretID、SecretKey 和 SessionToken
+	CredentialProcess string
+	Bucket            string
+	Region            string
+	MaxThread         int
+	PartSize          int
+	Retry             int
+	Timeout           int
+	Schema            string
+	Verify            string
+	Anonymous         bool
+	// URLBase 输出文件地址时使用的域名，可以包含协议和路径前缀，如 https://cdn.example.com/blog，
+	// 为空时使用 bucket 默认域名。只影响输出的地址，请求 COS API 仍然使用 bucket 默认域名
+	URLBase string
+	// KeyTemplate 生成对象 key 的模板，支持的占位符见 RenderKey
+	KeyTemplate string
+	// Dedup 是否按文件内容去重，相同内容的文件只上传一次
+	Dedup bool
+	// OnConflict COS 中已存在同名文件时的处理方式: fail, rename, overwrite, skip
+	OnConflict string
+
+	// 上传文件时默认设置的请求头，可以被命令行参数覆盖
+	CacheControl       string
+	ContentDisposition string
+	// Expires HTTP 日期或相对上传时间的时长，如 30d
+	Expires      string
+	StorageClass string
+	Meta         map[string]string
+
+	// DefaultFormat 输出文件地址的格式: url, markdown, html, bbcode, org, rst, custom
+	DefaultFormat string
+	// FormatTemplate DefaultFormat 为 custom 时使用的 text/template 模板
+	FormatTemplate string
+	// CopyResult 上传后是否将文件地址复制到剪切板
+	CopyResult bool
+	// ServeToken cosp serve 校验请求使用的 Bearer token，为空时不校验
+	ServeToken string
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig() *COSConfig {
+	return &COSConfig{
+		Profile:       DefaultProfile,
+		MaxThread:     5,
+		PartSize:      1,
+		Retry:         5,
+		Timeout:       60,
+		Schema:        "https",
+		Verify:        "md5",
+		Anonymous:     false,
+		KeyTemplate:   DefaultKeyTemplate,
+		OnConflict:    ConflictRename,
+		DefaultFormat: FormatURL,
+	}
+}
+
+// LoadConfig 加载配置并检查必填字段
+//
+// 配置按以下顺序叠加: 默认值 < 配置文件 < COS_* 环境变量。
+// 所有必填字段都通过环境变量提供时，可以不使用配置文件。
+func LoadConfig() (*COSConfig, error) {
+	config, err := ReadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := config.checkRequired(); err != nil {
+		if config.ConfigFile == "" {
+			configPath, _, _ := ConfigPath()
+			return nil, fmt.Errorf("配置文件不存在: %s", configPath)
+		}
+		return nil, err
+	}
+	return config, nil
+}
+
+// ReadConfig 读取并合并配置文件和环境变量中的配置，不检查必填字段
+func ReadConfig() (*COSConfig, error) {
+	config, err := ReadConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	if err := config.applyEnv(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// ReadConfigFile 只读取配置文件中的配置，不合并环境变量，不检查必填字段
+func ReadConfigFile() (*COSConfig, error) {
+	configPath, exists, err := ConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	config := DefaultConfig()
+	if exists {
+		if err := config.loadFile(configPath); err != nil {
+			return nil, err
+		}
+	} else {
+		config.Profile = resolveProfile(nil)
+	}
+	return config, nil
+}
+
+// checkRequired 检查必填字段，使用 credential_process 或匿名模式时不需要 secret_id 和 secret_key
+func (c *COSConfig) checkRequired() error {
+	if c.Anonymous {
+		if c.Bucket == "" || c.Region == "" {
+			return fmt.Errorf("配置文件 [%s] 缺少必要字段: bucket, region", c.Profile)
+		}
+		return nil
+	}
+	hasCredential := c.CredentialProcess != "" || (c.SecretID != "" && c.SecretKey != "")
+	if !hasCredential || c.Bucket == "" || c.Region == "" {
+		return fmt.Errorf("配置文件 [%s] 缺少必要字段: secret_id, secret_key（或 credential_process）, bucket, region", c.Profile)
+	}
+	return nil
+}
+
+// ConfigPath 返回配置文件路径以及该文件是否存在
+//
+// 查找顺序: --config 参数 > COSP_CONFIG 环境变量 > ~/.cos.conf > $XDG_CONFIG_HOME/cosp/config。
+// 都不存在时返回 ~/.cos.conf。
+func ConfigPath() (string, bool, error) {
+	if explicit := explicitConfigPath(); explicit != "" {
+		if _, err := os.Stat(explicit); err != nil {
+			if os.IsNotExist(err) {
+				return "", false, fmt.Errorf("配置文件不存在: %s", explicit)
+			}
+			return "", false, fmt.Errorf("无法读取配置文件: %v", err)
+		}
+		return explicit, true, nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", false, fmt.Errorf("无法获取用户主目录: %v", err)
+	}
+	homeConfig := filepath.Join(homeDir, ".cos.conf")
+	if fileExists(homeConfig) {
+		return homeConfig, true, nil
+	}
+
+	xdgConfigHome := os.Getenv("XDG_CONFIG_HOME")
+	if xdgConfigHome == "" {
+		xdgConfigHome = filepath.Join(homeDir, ".config")
+	}
+	xdgConfig := filepath.Join(xdgConfigHome, "cosp", "config")
+	if fileExists(xdgConfig) {
+		return xdgConfig, true, nil
+	}
+
+	return homeConfig, false, nil
+}
+
+// explicitConfigPath 返回通过 --config 参数或 COSP_CONFIG 环境变量指定的配置文件
+func explicitConfigPath() string {
+	if configFile != "" {
+		return configFile
+	}
+	return os.Getenv("COSP_CONFIG")
+}
+
+// WritableConfigPath 返回写入配置时使用的文件路径，与 ConfigPath 不同，
+// 通过 --config 或 COSP_CONFIG 指定的文件不存在时不会报错
+func WritableConfigPath() (string, error) {
+	if explicit := explicitConfigPath(); explicit != "" {
+		return explicit, nil
+	}
+	configPath, _, err := ConfigPath()
+	return configPath, err
+}
+
+// ResolveProfile 返回当前选中的 profile 名称，配置文件中可以不存在该配置节
+func ResolveProfile() (string, error) {
+	configPath, exists, err := ConfigPath()
+	if err != nil || !exists {
+		return resolveProfile(nil), err
+	}
+	cfg, err := ini.Load(configPath)
+	if err != nil {
+		return "", fmt.Errorf("解析配置文件失败: %v", err)
+	}
+	return resolveProfile(cfg), nil
+}
+
+// SaveProfile 将配置项写入配置文件中的指定配置节，值为空的配置项会被删除
+//
+// 配置文件中包含密钥，写入后文件权限设置为 0600。
+func SaveProfile(configPath, name string, keys []string, values map[string]string) error {
+	cfg, err := ini.LooseLoad(configPath)
+	if err != nil {
+		return fmt.Errorf("解析配置文件失败: %v", err)
+	}
+
+	section := cfg.Section(name)
+	for _, key := range keys {
+		if values[key] == "" {
+			section.DeleteKey(key)
+			continue
+		}
+		section.Key(key).SetValue(values[key])
+	}
+
+	var buf bytes.Buffer
+	if _, err := cfg.WriteTo(&buf); err != nil {
+		return fmt.Errorf("生成配置文件失败: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
+		return fmt.Errorf("创建配置目录失败: %v", err)
+	}
+	if err := os.WriteFile(configPath, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("写入配置文件失败: %v", err)
+	}
+	// 文件已存在时 WriteFile 不会修改权限
+	return os.Chmod(configPath, 0600)
+}
+
+// ConfigKeys 返回支持的配置项名称
+func ConfigKeys() []string {
+	return append([]string(nil), configKeys...)
+}
+
+// fileExists 判断文件是否存在
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// loadFile 从配置文件中读取选中的 profile
+func (c *COSConfig) loadFile(configPath string) error {
+	cfg, err := ini.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("解析配置文件失败: %v", err)
+	}
+
+	c.Profile = resolveProfile(cfg)
+
+	section, err := cfg.GetSection(c.Profile)
+	if err != nil {
+		return fmt.Errorf("配置文件 %s 中不存在 profile: [%s]", configPath, c.Profile)
+	}
+
+	// 其他 profile 中没有设置的字段沿用 [common] 中的值
+	if c.Profile != DefaultProfile {
+		if common, err := cfg.GetSection(DefaultProfile); err == nil {
+			if err := c.applySection(common); err != nil {
+				return err
+			}
+		}
+	}
+	if err := c.applySection(section); err != nil {
+		return err
+	}
+
+	c.ConfigFile = configPath
+	return nil
+}
+
+// resolveProfile 确定要使用的配置节，优先级: --profile 参数 > COSP_PROFILE 环境变量 > default_profile > [common]
+func resolveProfile(cfg *ini.File) string {
+	if profile != "" {
+		return profile
+	}
+	if val := os.Getenv("COSP_PROFILE"); val != "" {
+		return val
+	}
+	if cfg == nil {
+		return DefaultProfile
+	}
+	// default_profile 可以写在配置文件开头（不属于任何配置节），也可以写在 [common] 中
+	if val := cfg.Section(ini.DefaultSection).Key("default_profile").String(); val != "" {
+		return val
+	}
+	if common, err := cfg.GetSection(DefaultProfile); err == nil {
+		if val := common.Key("default_profile").String(); val != "" {
+			return val
+		}
+	}
+	return DefaultProfile
+}
+
+// applySection 使用配置节中出现的字段覆盖当前配置
+func (c *COSConfig) applySection(section *ini.Section) error {
+	for _, key := range section.Keys() {
+		// 值为空的字段视为未设置
+		if key.String() == "" {
+			continue
+		}
+		if err := c.Set(key.Name(), key.String()); err != nil {
+			return fmt.Errorf("配置文件 [%s] 中的 %s 无效: %v", section.Name(), key.Name(), err)
+		}
+	}
+	return nil
+}
+
+// applyEnv 使用 COS_* 环境变量覆盖配置
+func (c *COSConfig) applyEnv() error {
+	for _, name := range configKeys {
+		env := "COS_" + strings.ToUpper(name)
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+		if err := c.Set(name, value); err != nil {
+			return fmt.Errorf("环境变量 %s 无效: %v", env, err)
+		}
+	}
+	return nil
+}
+
+// Set 设置单个配置项，未知的配置项会被忽略
+func (c *COSConfig) Set(name, value string) error {
+	switch name {
+	case "secret_id":
+		c.SecretID = value
+	case "secret_key":
+		c.SecretKey = value
+	case "session_token":
+		c.SessionToken = value
+	case "credential_process":
+		c.CredentialProcess = value
+	case "bucket":
+		c.Bucket = value
+	case "region":
+		c.Region = value
+	case "max_thread":
+		return setPositiveInt(&c.MaxThread, value)
+	case "part_size":
+		return setPositiveInt(&c.PartSize, value)
+	case "retry":
+		val, err := strconv.Atoi(value)
+		if err != nil || val < 0 {
+			return fmt.Errorf("必须是大于等于 0 的整数")
+		}
+		c.Retry = val
+	case "timeout":
+		return setPositiveInt(&c.Timeout, value)
+	case "schema":
+		if value != "" {
+			c.Schema = value
+		}
+	case "verify":
+		switch val := strings.ToLower(value); val {
+		case VerifyMD5, VerifyCRC64, VerifyNone:
+			c.Verify = val
+		default:
+			return fmt.Errorf("可选值: md5, crc64, none")
+		}
+	case "anonymous":
+		val, err := parseBool(value)
+		if err != nil {
+			return err
+		}
+		c.Anonymous = val
+	// domain 是 url_base 的别名
+	case "url_base", "domain":
+		if err := checkURLBase(value); err != nil {
+			return err
+		}
+		c.URLBase = value
+	case "key_template":
+		if err := CheckKeyTemplate(value); err != nil {
+			return err
+		}
+		c.KeyTemplate = value
+	case "dedup":
+		val, err := parseBool(value)
+		if err != nil {
+			return err
+		}
+		c.Dedup = val
+	case "on_conflict":
+		val, err := ParseConflictPolicy(value)
+		if err != nil {
+			return err
+		}
+		c.OnConflict = val
+	case "cache_control":
+		c.CacheControl = value
+	case "content_disposition":
+		c.ContentDisposition = value
+	case "expires":
+		if value != "" {
+			if _, err := ParseExpires(value, time.Now()); err != nil {
+				return err
+			}
+		}
+		c.Expires = value
+	case "storage_class":
+		if value != "" {
+			class, err := ParseStorageClass(value)
+			if err != nil {
+				return err
+			}
+			value = class
+		}
+		c.StorageClass = value
+	case "meta":
+		meta, err := ParseMeta(value)
+		if err != nil {
+			return err
+		}
+		c.Meta = meta
+	case "default_format":
+		val, err := ParseLinkFormat(value)
+		if err != nil {
+			return err
+		}
+		c.DefaultFormat = val
+	case "format_template":
+		if value != "" {
+			if _, err := NewLinkFormatter(FormatCustom, value); err != nil {
+				return err
+			}
+		}
+		c.FormatTemplate = value
+	case "copy_result":
+		val, err := parseBool(value)
+		if err != nil {
+			return err
+		}
+		c.CopyResult = val
+	case "serve_token":
+		c.ServeToken = value
+	}
+	return nil
+}
+
+// Value 返回配置项当前的值
+func (c *COSConfig) Value(name string) string {
+	switch name {
+	case "secret_id":
+		return c.SecretID
+	case "secret_key":
+		return c.SecretKey
+	case "session_token":
+		return c.SessionToken
+	case "credential_process":
+		return c.CredentialProcess
+	case "bucket":
+		return c.Bucket
+	case "region":
+		return c.Region
+	case "max_thread":
+		return strconv.Itoa(c.MaxThread)
+	case "part_size":
+		return strconv.Itoa(c.PartSize)
+	case "retry":
+		return strconv.Itoa(c.Retry)
+	case "timeout":
+		return strconv.Itoa(c.Timeout)
+	case "schema":
+		return c.Schema
+	case "verify":
+		return c.Verify
+	case "anonymous":
+		return strconv.FormatBool(c.Anonymous)
+	case "url_base", "domain":
+		return c.URLBase
+	case "key_template":
+		return c.KeyTemplate
+	case "dedup":
+		return strconv.FormatBool(c.Dedup)
+	case "on_conflict":
+		return c.OnConflict
+	case "cache_control":
+		return c.CacheControl
+	case "content_disposition":
+		return c.ContentDisposition
+	case "expires":
+		return c.Expires
+	case "storage_class":
+		return c.StorageClass
+	case "meta":
+		return formatMeta(c.Meta)
+	case "default_format":
+		return c.DefaultFormat
+	case "format_template":
+		return c.FormatTemplate
+	case "copy_result":
+		return strconv.FormatBool(c.CopyResult)
+	case "serve_token":
+		return c.ServeToken
+	}
+	return ""
+}
+
+var (
+	bucketPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*-[0-9]+$`)
+	regionPattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+)
+
+// Validate 检查配置是否完整、取值是否在合理范围内，返回发现的所有问题
+func (c *COSConfig) Validate() []string {
+	var problems []string
+	if err := c.checkRequired(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if c.Bucket != "" && !bucketPattern.MatchString(c.Bucket) {
+		problems = append(problems, fmt.Sprintf("bucket = %s 格式不正确，应为 <名称>-<APPID>，如 images-1250000000", c.Bucket))
+	}
+	if c.Region != "" && !regionPattern.MatchString(c.Region) {
+		problems = append(problems, fmt.Sprintf("region = %s 格式不正确，如 ap-beijing", c.Region))
+	}
+	if c.Schema != "http" && c.Schema != "https" {
+		problems = append(problems, fmt.Sprintf("schema = %s 无效，可选值: http, https", c.Schema))
+	}
+	if c.MaxThread > 100 {
+		problems = append(problems, fmt.Sprintf("max_thread = %d 过大，取值范围 1-100", c.MaxThread))
+	}
+	// COS 单个分块最大为 5GB
+	if c.PartSize > 5120 {
+		problems = append(problems, fmt.Sprintf("part_size = %d 过大，取值范围 1-5120（MB）", c.PartSize))
+	}
+	if c.Retry > 20 {
+		problems = append(problems, fmt.Sprintf("retry = %d 过大，取值范围 0-20", c.Retry))
+	}
+	if c.Timeout > 3600 {
+		problems = append(problems, fmt.Sprintf("timeout = %d 过大，取值范围 1-3600（秒）", c.Timeout))
+	}
+	if c.DefaultFormat == FormatCustom && c.FormatTemplate == "" {
+		problems = append(problems, "default_format = custom 时需要设置 format_template")
+	}
+	return problems
+}
+
+// checkURLBase 检查 url_base 的格式，可以省略协议，如 cdn.example.com/blog
+func checkURLBase(value string) error {
+	if value == "" {
+		return nil
+	}
+	if !strings.Contains(value, "://") {
+		value = "https://" + value
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("格式不正确，如 https://cdn.example.com 或 cdn.example.com/blog")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("协议只能是 http 或 https")
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("不能包含查询参数")
+	}
+	return nil
+}
+
+// ParseConflictPolicy 解析同名文件的处理方式，用于配置项 on_conflict 和 --on-conflict 参数
+func ParseConflictPolicy(value string) (string, error) {
+	switch val := strings.ToLower(value); val {
+	case ConflictFail, ConflictRename, ConflictOverwrite, ConflictSkip:
+		return val, nil
+	default:
+		return "", fmt.Errorf("可选值: fail, rename, overwrite, skip")
+	}
+}
+
+// setPositiveInt 将 value 解析为正整数后写入 dst
+func setPositiveInt(dst *int, value string) error {
+	val, err := strconv.Atoi(value)
+	if err != nil || val <= 0 {
+		return fmt.Errorf("必须是大于 0 的整数")
+	}
+	*dst = val
+	return nil
+}
+
+// parseBool 解析布尔值，支持 true/false、yes/no、on/off、1/0
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "on", "1":
+		return true, nil
+	case "false", "no", "off", "0":
+		return false, nil
+	default:
+		return false, fmt.Errorf("必须是 true 或 false")
+	}
+}
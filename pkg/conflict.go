@@ -0,0 +1,161 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	logger "github.com/bwangelme/cosp/log"
+
+	"github.com/tencentyun/cos-go-sdk-v5"
+)
+
+// COS 中已存在同名文件时的处理方式，对应配置项 on_conflict
+const (
+	// ConflictFail 报错退出
+	ConflictFail = "fail"
+	// ConflictRename 在文件名后追加 -1、-2 等后缀
+	ConflictRename = "rename"
+	// ConflictOverwrite 覆盖已有文件
+	ConflictOverwrite = "overwrite"
+	// ConflictSkip 跳过上传
+	ConflictSkip = "skip"
+)
+
+// maxRenameAttempts 重命名时最多尝试的后缀数量
+const maxRenameAttempts = 100
+
+// ErrObjectExists COS 中已存在同名文件
+var ErrObjectExists = errors.New("文件已存在")
+
+// checkConflict 将设置 x-cos-forbid-overwrite 后 COS 返回的 409 转换为 ErrObjectExists
+func checkConflict(key string, err error) error {
+	if cosErr, ok := cos.IsCOSError(err); ok && cosErr.Response != nil && cosErr.Response.StatusCode == http.StatusConflict {
+		return fmt.Errorf("%w: %s", ErrObjectExists, key)
+	}
+	return err
+}
+
+// isForbidden 判断错误是否为 COS 返回的 403
+func isForbidden(err error) bool {
+	cosErr, ok := cos.IsCOSError(err)
+	return ok && cosErr.Response != nil && cosErr.Response.StatusCode == http.StatusForbidden
+}
+
+// resolveConflict 处理上传请求的错误，409 转换为 ErrObjectExists
+//
+// retried 为 true 时请求经过了重试，409 可能是之前已经成功、但响应丢失的请求造成的。
+// 此时比较 COS 中对象与本地内容，一致时视为上传成功，返回 HEAD 的响应和 nil。
+func (c *Client) resolveConflict(ctx context.Context, key string, err error, retried bool, r io.ReaderAt, size int64) (*cos.Response, error) {
+	err = checkConflict(key, err)
+	if !retried || !errors.Is(err, ErrObjectExists) {
+		return nil, err
+	}
+	resp, same := c.sameContent(ctx, key, r, size)
+	if !same {
+		return nil, err
+	}
+	logger.L.Debugf("重试前的请求已经上传成功: %s", key)
+	return resp, nil
+}
+
+// sameContent 判断 COS 中的对象是否与本地内容一致，优先比较 CRC64，没有 CRC64 时比较 MD5
+func (c *Client) sameContent(ctx context.Context, key string, r io.ReaderAt, size int64) (*cos.Response, bool) {
+	resp, err := c.HeadObject(ctx, key)
+	if err != nil {
+		logger.L.Debugf("获取文件信息失败，无法确认 COS 中的文件是否为本次上传: %v", err)
+		return nil, false
+	}
+	if resp.ContentLength != size {
+		return resp, false
+	}
+	if value := resp.Header.Get("x-cos-hash-crc64ecma"); value != "" {
+		remote, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return resp, false
+		}
+		sum, err := crc64Sum(io.NewSectionReader(r, 0, size))
+		return resp, err == nil && sum == remote
+	}
+	etag := resp.Header.Get("ETag")
+	// 分块上传的对象 ETag 不是内容的 MD5
+	if etag == "" || strings.Contains(etag, "-") {
+		return resp, false
+	}
+	sum, err := md5Sum(io.NewSectionReader(r, 0, size))
+	return resp, err == nil && checkETag(resp.Header, sum) == nil
+}
+
+// ConflictKey 返回第 n 次重命名使用的 key，如 a/b.png 重命名为 a/b-1.png
+func ConflictKey(key string, n int) string {
+	ext := path.Ext(key)
+	// 目录名中的 . 不是扩展名
+	if strings.Contains(ext, "/") {
+		ext = ""
+	}
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(key, ext), n, ext)
+}
+
+// UploadFunc 使用指定的 key 上传文件，返回实际使用的 key
+type UploadFunc func(ctx context.Context, key string, opt *UploadOptions) (string, error)
+
+// UploadWithPolicy 按 policy 处理同名文件后调用 upload 上传，返回实际使用的 key 以及是否跳过了上传
+//
+// 除 overwrite 外，上传前先检查文件是否存在，上传时设置 x-cos-forbid-overwrite，
+// 避免检查之后其他人上传的同名文件被覆盖。只有上传权限、没有 HeadObject 权限的密钥
+// 检查时会返回 403，此时不再检查，只依靠 x-cos-forbid-overwrite 返回的 409 判断同名文件。
+// opt 为传给 upload 的上传参数，可以为 nil。
+func (c *Client) UploadWithPolicy(ctx context.Context, key, policy string, opt *UploadOptions, upload UploadFunc) (string, bool, error) {
+	uploadOpt := &UploadOptions{}
+	if opt != nil {
+		*uploadOpt = *opt
+	}
+	uploadOpt.ForbidOverwrite = policy != ConflictOverwrite
+
+	if policy == ConflictOverwrite {
+		key, err := upload(ctx, key, uploadOpt)
+		return key, false, err
+	}
+
+	candidate := key
+	canHead := true
+	for n := 1; n <= maxRenameAttempts; n++ {
+		exists := false
+		if canHead {
+			var err error
+			exists, err = c.ObjectExists(ctx, candidate)
+			if isForbidden(err) {
+				logger.L.Debugf("没有 HeadObject 权限，直接上传: %v", err)
+				canHead = false
+			} else if err != nil {
+				return candidate, false, fmt.Errorf("检查文件是否存在失败: %v", err)
+			}
+		}
+		if !exists {
+			uploaded, err := upload(ctx, candidate, uploadOpt)
+			if !errors.Is(err, ErrObjectExists) {
+				return uploaded, false, err
+			}
+			logger.L.Debugf("上传时 COS 中出现了同名文件: %s", uploaded)
+			// 断点续传时实际使用的 key 可能与 candidate 不同
+			candidate = uploaded
+		}
+
+		switch policy {
+		case ConflictSkip:
+			logger.L.Debugf("文件已存在，跳过上传: %s", candidate)
+			return candidate, true, nil
+		case ConflictRename:
+			candidate = ConflictKey(key, n)
+			logger.L.Debugf("文件已存在，重命名为: %s", candidate)
+		default:
+			return candidate, false, fmt.Errorf("%w: %s（可以使用 --on-conflict=rename|overwrite|skip）", ErrObjectExists, candidate)
+		}
+	}
+	return candidate, false, fmt.Errorf("%w: 尝试 %d 次重命名后仍然冲突: %s", ErrObjectExists, maxRenameAttempts, key)
+}
@@ -1,105 +1,32 @@
 package pkg
 
 import (
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
+	"strings"
+	"time"
+
+	logger "github.com/bwangelme/cosp/log"
 
 	"github.com/tencentyun/cos-go-sdk-v5"
-	"gopkg.in/ini.v1"
 )
 
-// COSConfig 配置结构体
-type COSConfig struct {
-	SecretID  string
-	SecretKey string
-	Bucket    string
-	Region    string
-	MaxThread int
-	PartSize  int
-	Retry     int
-	Timeout   int
-	Schema    string
-	Verify    string
-	Anonymous bool
-}
-
-// DefaultConfig 返回默认配置
-func DefaultConfig() *COSConfig {
-	return &COSConfig{
-		MaxThread: 5,
-		PartSize:  1,
-		Retry:     5,
-		Timeout:   60,
-		Schema:    "https",
-		Verify:    "md5",
-		Anonymous: false,
-	}
-}
-
-// LoadConfig 从配置文件加载配置
-func LoadConfig() (*COSConfig, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, fmt.Errorf("无法获取用户主目录: %v", err)
-	}
-
-	configPath := filepath.Join(homeDir, ".cos.conf")
-
-	// 如果配置文件不存在，返回错误
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("配置文件不存在: %s", configPath)
-	}
+// ErrAnonymous 匿名模式下执行上传、删除等需要密钥的操作时返回
+var ErrAnonymous = errors.New("当前为匿名模式（anonymous = true），只能执行列出文件等只读操作，上传、删除等操作需要配置 secret_id 和 secret_key 并关闭 anonymous")
 
-	cfg, err := ini.Load(configPath)
-	if err != nil {
-		return nil, fmt.Errorf("解析配置文件失败: %v", err)
-	}
-
-	common := cfg.Section("common")
-	config := DefaultConfig()
-
-	// 读取必填字段
-	config.SecretID = common.Key("secret_id").String()
-	config.SecretKey = common.Key("secret_key").String()
-	config.Bucket = common.Key("bucket").String()
-	config.Region = common.Key("region").String()
-
-	// 验证必填字段
-	if config.SecretID == "" || config.SecretKey == "" || config.Bucket == "" || config.Region == "" {
-		return nil, fmt.Errorf("配置文件缺少必要字段: secret_id, secret_key, bucket, region")
-	}
-
-	// 读取可选字段
-	if val, err := common.Key("max_thread").Int(); err == nil {
-		config.MaxThread = val
-	}
-	if val, err := common.Key("part_size").Int(); err == nil {
-		config.PartSize = val
-	}
-	if val, err := common.Key("retry").Int(); err == nil {
-		config.Retry = val
-	}
-	if val, err := common.Key("timeout").Int(); err == nil {
-		config.Timeout = val
-	}
-	if val := common.Key("schema").String(); val != "" {
-		config.Schema = val
-	}
-	if val := common.Key("verify").String(); val != "" {
-		config.Verify = val
-	}
-	if val, err := common.Key("anonymous").Bool(); err == nil {
-		config.Anonymous = val
-	}
+// Client 封装 COS 客户端及其使用的配置
+type Client struct {
+	*cos.Client
+	Config *COSConfig
 
-	return config, nil
+	credentials CredentialProvider
 }
 
 // NewClient 创建COS客户端
-func NewClient(config *COSConfig) (*cos.Client, error) {
+func NewClient(config *COSConfig) (*Client, error) {
 	// 构建 bucket URL
 	bucketURL := fmt.Sprintf("%s://%s.cos.%s.myqcloud.com", config.Schema, config.Bucket, config.Region)
 
@@ -110,15 +37,58 @@ func NewClient(config *COSConfig) (*cos.Client, error) {
 
 	baseURL := &cos.BaseURL{BucketURL: u}
 
-	// 创建客户端
-	client := cos.NewClient(baseURL, &http.Client{
-		Transport: &cos.AuthorizationTransport{
-			SecretID:  config.SecretID,
-			SecretKey: config.SecretKey,
-		},
-	})
+	// 创建客户端，每次请求前从 CredentialProvider 获取密钥，支持临时密钥过期前自动刷新。
+	// 匿名模式下请求不签名，只能访问公有读的 bucket
+	var credentials CredentialProvider
+	var transport http.RoundTripper = newTransport(config.timeout())
+	if config.Anonymous {
+		logger.L.Debug("匿名模式，请求不携带签名")
+	} else {
+		credentials = NewCredentialProvider(config)
+		transport = &credentialTransport{
+			provider: credentials,
+			auth:     &cos.AuthorizationTransport{Transport: transport},
+		}
+	}
+	client := cos.NewClient(baseURL, &http.Client{Transport: transport})
+
+	// 由 Client.retry 统一处理重试，关闭 SDK 内置的重试
+	client.Conf.RetryOpt.Count = 1
+	// 由 Client.verifyObject 按 verify 配置校验上传结果，关闭 SDK 内置的 CRC 校验
+	client.Conf.EnableCRC = false
 
-	return client, nil
+	return &Client{Client: client, Config: config, credentials: credentials}, nil
+}
+
+// CheckWritable 检查是否可以执行上传、删除等写操作，匿名模式下返回 ErrAnonymous
+func (c *Client) CheckWritable() error {
+	if c.Config.Anonymous {
+		return ErrAnonymous
+	}
+	return nil
+}
+
+// newTransport 创建带有连接、TLS 握手和响应头超时的 HTTP Transport
+func newTransport(timeout time.Duration) *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   timeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// timeout 返回单次请求的超时时间
+func (c *COSConfig) timeout() time.Duration {
+	return time.Duration(c.Timeout) * time.Second
 }
 
 // GetBucketURL 获取bucket URL
@@ -126,8 +96,29 @@ func (c *COSConfig) GetBucketURL() string {
 	return fmt.Sprintf("%s://%s.cos.%s.myqcloud.com", c.Schema, c.Bucket, c.Region)
 }
 
-// NewClientWithFallback 从配置文件创建客户端
-func NewClientWithFallback() (*cos.Client, string, error) {
+// GetURLBase 获取输出文件地址时使用的 URL 前缀，配置了 url_base 时使用 url_base，否则使用 bucket URL
+func (c *COSConfig) GetURLBase() string {
+	if c.URLBase == "" {
+		return c.GetBucketURL()
+	}
+	base := c.URLBase
+	if !strings.Contains(base, "://") {
+		base = c.Schema + "://" + base
+	}
+	return strings.TrimRight(base, "/")
+}
+
+// ObjectURL 返回对象的访问地址，key 中的特殊字符会被转义
+func (c *COSConfig) ObjectURL(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return c.GetURLBase() + "/" + strings.Join(segments, "/")
+}
+
+// NewClientWithFallback 从配置文件创建客户端，返回的 URL 是输出文件地址时使用的 URL 前缀
+func NewClientWithFallback() (*Client, string, error) {
 	// 从配置文件读取
 	config, err := LoadConfig()
 	if err != nil {
@@ -138,5 +129,5 @@ func NewClientWithFallback() (*cos.Client, string, error) {
 	if err != nil {
 		return nil, "", err
 	}
-	return client, config.GetBucketURL(), nil
+	return client, config.GetURLBase(), nil
 }
@@ -0,0 +1,186 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	logger "github.com/bwangelme/cosp/log"
+
+	"github.com/tencentyun/cos-go-sdk-v5"
+)
+
+// credentialRefreshWindow 临时密钥在过期前多久刷新
+const credentialRefreshWindow = 5 * time.Minute
+
+// ErrCredential 获取密钥失败，请求没有发出，不会重试
+var ErrCredential = errors.New("获取密钥失败")
+
+// Credential 访问 COS 使用的密钥
+type Credential struct {
+	SecretID     string
+	SecretKey    string
+	SessionToken string
+	// Expiration 临时密钥的过期时间，零值表示永不过期
+	Expiration time.Time
+}
+
+// expiresSoon 判断密钥是否即将过期
+func (c *Credential) expiresSoon() bool {
+	return !c.Expiration.IsZero() && time.Until(c.Expiration) < credentialRefreshWindow
+}
+
+// CredentialProvider 提供访问 COS 使用的密钥
+type CredentialProvider interface {
+	Retrieve(ctx context.Context) (*Credential, error)
+}
+
+// StaticProvider 使用配置中固定的密钥
+type StaticProvider struct {
+	Credential Credential
+}
+
+// Retrieve 返回固定的密钥
+func (p *StaticProvider) Retrieve(ctx context.Context) (*Credential, error) {
+	return &p.Credential, nil
+}
+
+// ProcessProvider 通过执行外部命令获取密钥，并在密钥过期前重新执行命令刷新
+//
+// 命令需要向标准输出打印 JSON，支持以下两种格式:
+//
+//	{"SecretId": "...", "SecretKey": "...", "SessionToken": "...", "Expiration": "2024-01-15T10:30:00Z"}
+//	{"Credentials": {"TmpSecretId": "...", "TmpSecretKey": "...", "Token": "..."}, "ExpiredTime": 1705314600}
+//
+// 第二种格式即 tccli sts GetFederationToken 等命令的输出。
+type ProcessProvider struct {
+	Command string
+
+	mu         sync.Mutex
+	credential *Credential
+}
+
+// NewProcessProvider 创建通过外部命令获取密钥的 CredentialProvider
+func NewProcessProvider(command string) *ProcessProvider {
+	return &ProcessProvider{Command: command}
+}
+
+// Retrieve 返回缓存的密钥，密钥不存在或即将过期时重新执行命令获取
+func (p *ProcessProvider) Retrieve(ctx context.Context) (*Credential, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.credential != nil && !p.credential.expiresSoon() {
+		return p.credential, nil
+	}
+
+	logger.L.Debugf("执行 credential_process 获取密钥: %s", p.Command)
+	credential, err := p.run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if credential.Expiration.IsZero() {
+		logger.L.Debug("credential_process 获取密钥成功，未返回过期时间")
+	} else {
+		logger.L.Debugf("credential_process 获取密钥成功，过期时间: %s", credential.Expiration.Local().Format("2006-01-02 15:04:05"))
+	}
+	p.credential = credential
+	return credential, nil
+}
+
+// processOutput credential_process 命令的输出
+type processOutput struct {
+	SecretID     string `json:"SecretId"`
+	SecretKey    string `json:"SecretKey"`
+	SessionToken string `json:"SessionToken"`
+	Expiration   string `json:"Expiration"`
+
+	Credentials *struct {
+		TmpSecretID  string `json:"TmpSecretId"`
+		TmpSecretKey string `json:"TmpSecretKey"`
+		Token        string `json:"Token"`
+	} `json:"Credentials"`
+	ExpiredTime int64 `json:"ExpiredTime"`
+}
+
+func (p *ProcessProvider) run(ctx context.Context) (*Credential, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", p.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", p.Command)
+	}
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("执行 credential_process 失败: %v", err)
+	}
+
+	var output processOutput
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return nil, fmt.Errorf("解析 credential_process 输出失败: %v", err)
+	}
+
+	credential := &Credential{
+		SecretID:     output.SecretID,
+		SecretKey:    output.SecretKey,
+		SessionToken: output.SessionToken,
+	}
+	if output.Credentials != nil {
+		credential.SecretID = output.Credentials.TmpSecretID
+		credential.SecretKey = output.Credentials.TmpSecretKey
+		credential.SessionToken = output.Credentials.Token
+	}
+	if credential.SecretID == "" || credential.SecretKey == "" {
+		return nil, fmt.Errorf("credential_process 输出中缺少 SecretId 或 SecretKey")
+	}
+
+	switch {
+	case output.Expiration != "":
+		expiration, err := time.Parse(time.RFC3339, strings.TrimSpace(output.Expiration))
+		if err != nil {
+			return nil, fmt.Errorf("解析 credential_process 输出的 Expiration 失败: %v", err)
+		}
+		credential.Expiration = expiration
+	case output.ExpiredTime > 0:
+		credential.Expiration = time.Unix(output.ExpiredTime, 0)
+	}
+	return credential, nil
+}
+
+// NewCredentialProvider 根据配置创建 CredentialProvider
+func NewCredentialProvider(config *COSConfig) CredentialProvider {
+	if config.CredentialProcess != "" {
+		return NewProcessProvider(config.CredentialProcess)
+	}
+	return &StaticProvider{Credential: Credential{
+		SecretID:     config.SecretID,
+		SecretKey:    config.SecretKey,
+		SessionToken: config.SessionToken,
+	}}
+}
+
+// credentialTransport 在每次请求前从 CredentialProvider 获取密钥并签名
+type credentialTransport struct {
+	provider CredentialProvider
+	auth     *cos.AuthorizationTransport
+}
+
+func (t *credentialTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	credential, err := t.provider.Retrieve(req.Context())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCredential, err)
+	}
+	t.auth.SetCredential(credential.SecretID, credential.SecretKey, credential.SessionToken)
+	return t.auth.RoundTrip(req)
+}
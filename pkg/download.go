@@ -0,0 +1,229 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	logger "github.com/bwangelme/cosp/log"
+
+	"github.com/tencentyun/cos-go-sdk-v5"
+)
+
+// DownloadPartSuffix 下载中的文件在目标路径后追加的后缀，下载完成后重命名为目标路径
+const DownloadPartSuffix = ".cospart"
+
+// downloadState 记录分块下载的进度，与下载中的文件保存在同一目录，用于中断后继续下载
+type downloadState struct {
+	Key      string `json:"key"`
+	Size     int64  `json:"size"`
+	ETag     string `json:"etag"`
+	PartSize int64  `json:"part_size"`
+	Parts    []int  `json:"parts"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// load 读取下载进度，文件不存在或者与当前对象不一致时返回 false
+func (s *downloadState) load(key string, size int64, etag string, partSize int64) bool {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return false
+	}
+	var saved downloadState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		logger.L.Debugf("下载进度 %s 格式不正确，重新下载: %v", s.path, err)
+		return false
+	}
+	if saved.Key != key || saved.Size != size || saved.ETag != etag || saved.PartSize != partSize {
+		logger.L.Debugf("COS 中的文件已改变，重新下载: %s", key)
+		return false
+	}
+	s.Parts = saved.Parts
+	return true
+}
+
+// addPart 记录已下载的分块并保存进度
+func (s *downloadState) addPart(partNumber int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Parts = append(s.Parts, partNumber)
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
+
+// remove 删除下载进度
+func (s *downloadState) remove() {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		logger.L.Debugf("删除下载进度失败: %v", err)
+	}
+}
+
+// DownloadFile 下载对象并保存到 path，返回文件大小
+//
+// 数据先写入 path + DownloadPartSuffix，下载并校验完成后再重命名为 path。
+// 文件大小达到 MultipartThreshold 时按 part_size 分块，使用 max_thread 个协程并发下载，
+// resume 为 true 时沿用上次中断时已下载的分块。
+func (c *Client) DownloadFile(ctx context.Context, key, path string, resume bool) (int64, error) {
+	resp, err := c.HeadObject(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	size := resp.ContentLength
+	etag := resp.Header.Get("ETag")
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, fmt.Errorf("创建目录失败: %v", err)
+	}
+	partPath := path + DownloadPartSuffix
+	file, err := os.OpenFile(partPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("创建文件失败: %v", err)
+	}
+	defer file.Close()
+
+	state := &downloadState{Key: key, Size: size, ETag: etag, path: partPath + ".json"}
+	if size < MultipartThreshold {
+		err = c.downloadRange(ctx, key, etag, file, 0, size, false)
+	} else {
+		state.PartSize = c.partSize(size)
+		if !resume || !state.load(key, size, etag, state.PartSize) {
+			state.Parts = nil
+			state.remove()
+		}
+		err = c.downloadParts(ctx, key, file, state)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err := file.Truncate(size); err != nil {
+		return 0, fmt.Errorf("写入文件失败: %v", err)
+	}
+	if err := c.verifyDownload(file, resp.Header, size); err != nil {
+		// 内容与 COS 不一致，不能再用于继续下载
+		file.Close()
+		os.Remove(partPath)
+		state.remove()
+		return 0, err
+	}
+	if err := file.Close(); err != nil {
+		return 0, fmt.Errorf("写入文件失败: %v", err)
+	}
+	if err := os.Rename(partPath, path); err != nil {
+		return 0, fmt.Errorf("重命名文件失败: %v", err)
+	}
+	state.remove()
+
+	if modTime, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		os.Chtimes(path, time.Now(), modTime)
+	}
+	return size, nil
+}
+
+// downloadParts 并发下载尚未完成的分块，每个分块完成后保存下载进度
+func (c *Client) downloadParts(ctx context.Context, key string, file *os.File, state *downloadState) error {
+	partCount := int((state.Size + state.PartSize - 1) / state.PartSize)
+	done := map[int]bool{}
+	for _, partNumber := range state.Parts {
+		done[partNumber] = true
+	}
+	var todo []int
+	for partNumber := 1; partNumber <= partCount; partNumber++ {
+		if !done[partNumber] {
+			todo = append(todo, partNumber)
+		}
+	}
+	if len(done) > 0 {
+		logger.L.Debugf("跳过 %d 个已下载的分块", len(done))
+	}
+
+	err := runParts(ctx, todo, c.Config.MaxThread, func(ctx context.Context, partNumber int) error {
+		offset, length := partRange(partNumber, state.PartSize, state.Size)
+		err := c.downloadRange(ctx, key, state.ETag, file, offset, length, true)
+		if err == nil {
+			err = state.addPart(partNumber)
+		}
+		if err != nil {
+			return fmt.Errorf("下载第 %d 个分块失败: %v", partNumber, err)
+		}
+		logger.L.Debugf("分块 %d/%d 下载完成，大小: %d 字节", partNumber, partCount, length)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Ints(state.Parts)
+	return nil
+}
+
+// downloadRange 下载对象从 offset 开始的 length 字节并写入 file 的相同位置
+//
+// 请求带上 If-Match，下载过程中 COS 中的文件被修改时返回错误，避免拼接出新旧混合的内容。
+func (c *Client) downloadRange(ctx context.Context, key, etag string, file *os.File, offset, length int64, ranged bool) error {
+	header := http.Header{}
+	if etag != "" {
+		header.Set("If-Match", etag)
+	}
+	opt := &cos.ObjectGetOptions{XOptionHeader: &header}
+	if ranged {
+		opt.Range = fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+	}
+
+	_, err := c.retryTransfer(ctx, "下载文件", func(ctx context.Context) (*cos.Response, error) {
+		resp, err := c.Object.Get(ctx, key, opt)
+		if err != nil {
+			return resp, err
+		}
+		defer resp.Body.Close()
+		n, err := io.Copy(io.NewOffsetWriter(file, offset), resp.Body)
+		if err != nil {
+			return resp, err
+		}
+		if n != length {
+			return resp, fmt.Errorf("%w: 应为 %d 字节，实际收到 %d 字节", io.ErrUnexpectedEOF, length, n)
+		}
+		return resp, nil
+	})
+	if cosErr, ok := cos.IsCOSError(err); ok && cosErr.Response != nil && cosErr.Response.StatusCode == http.StatusPreconditionFailed {
+		return fmt.Errorf("下载过程中 COS 中的文件 %s 被修改，请重新下载", key)
+	}
+	return err
+}
+
+// verifyDownload 按配置的校验方式检查下载的内容
+//
+// 分块上传的对象 ETag 不是内容的 MD5，MD5 校验模式下改为比较 CRC64。
+func (c *Client) verifyDownload(r io.ReaderAt, header http.Header, size int64) error {
+	mode := c.Config.Verify
+	if mode == VerifyMD5 && strings.Contains(header.Get("ETag"), "-") {
+		mode = VerifyCRC64
+	}
+	switch mode {
+	case VerifyMD5:
+		sum, err := md5Sum(io.NewSectionReader(r, 0, size))
+		if err != nil {
+			return err
+		}
+		return checkETag(header, sum)
+	case VerifyCRC64:
+		sum, err := crc64Sum(io.NewSectionReader(r, 0, size))
+		if err != nil {
+			return err
+		}
+		return checkCRC64(header, sum)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// 输出文件地址的格式，对应 --format 参数和配置项 default_format
+const (
+	FormatURL      = "url"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatBBCode   = "bbcode"
+	FormatOrg      = "org"
+	FormatRST      = "rst"
+	// FormatCustom 使用 --template 参数或配置项 format_template 指定的模板
+	FormatCustom = "custom"
+)
+
+// linkFormats 支持的输出格式
+var linkFormats = []string{FormatURL, FormatMarkdown, FormatHTML, FormatBBCode, FormatOrg, FormatRST, FormatCustom}
+
+// linkTemplates 内置输出格式使用的模板
+var linkTemplates = map[string]string{
+	FormatURL:      "{{.URL}}",
+	FormatMarkdown: "![{{.Name}}]({{.URL}})",
+	FormatHTML:     `<img src="{{html .URL}}" alt="{{html .Name}}">`,
+	FormatBBCode:   "[img]{{.URL}}[/img]",
+	FormatOrg:      "[[{{.URL}}]]",
+	FormatRST:      ".. image:: {{.URL}}\n   :alt: {{.Name}}",
+}
+
+// Link 输出文件地址时模板可以使用的字段
+type Link struct {
+	// URL 文件地址，使用临时访问地址时为带签名的地址
+	URL string
+	// Key 文件在 COS 中的 key
+	Key string
+	// Name 不含扩展名的文件名，用作图片的替代文本
+	Name string
+	// Filename 本地文件名，没有本地文件名时为 key 中的文件名
+	Filename string
+	// Size 文件大小，单位字节
+	Size int64
+}
+
+// NewLink 创建输出文件地址使用的 Link，filename 为空时使用 key 中的文件名
+func NewLink(url, key, filename string, size int64) Link {
+	if filename == "" {
+		filename = path.Base(key)
+	}
+	return Link{
+		URL:      url,
+		Key:      key,
+		Name:     strings.TrimSuffix(filename, path.Ext(filename)),
+		Filename: filename,
+		Size:     size,
+	}
+}
+
+// ParseLinkFormat 检查输出格式，不区分大小写
+func ParseLinkFormat(value string) (string, error) {
+	format := strings.ToLower(value)
+	for _, f := range linkFormats {
+		if format == f {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("可选值: %s", strings.Join(linkFormats, ", "))
+}
+
+// LinkFormatter 按输出格式生成文件地址
+type LinkFormatter struct {
+	tmpl *template.Template
+}
+
+// NewLinkFormatter 创建 LinkFormatter，format 为 custom 时使用 custom 模板，
+// 模板可以使用 Link 的字段，如 ![{{.Name}}]({{.URL}})
+func NewLinkFormatter(format, custom string) (*LinkFormatter, error) {
+	text, ok := linkTemplates[format]
+	if format == FormatCustom {
+		if custom == "" {
+			return nil, fmt.Errorf("使用 custom 格式时需要通过 --template 或配置项 format_template 指定模板")
+		}
+		text, ok = custom, true
+	}
+	if !ok {
+		return nil, fmt.Errorf("不支持的输出格式 %s，可选值: %s", format, strings.Join(linkFormats, ", "))
+	}
+	tmpl, err := template.New(format).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("模板格式不正确: %v", err)
+	}
+	// 提前发现模板中不存在的字段
+	if err := tmpl.Execute(io.Discard, Link{}); err != nil {
+		return nil, fmt.Errorf("模板格式不正确: %v", err)
+	}
+	return &LinkFormatter{tmpl: tmpl}, nil
+}
+
+// Format 按输出格式生成文件地址
+func (f *LinkFormatter) Format(link Link) (string, error) {
+	var b strings.Builder
+	if err := f.tmpl.Execute(&b, link); err != nil {
+		return "", fmt.Errorf("生成输出内容失败: %v", err)
+	}
+	return b.String(), nil
+}
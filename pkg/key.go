@@ -0,0 +1,208 @@
+package pkg
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultKeyTemplate 默认的对象 key 模板，生成如 2024-01-15-143022.png 的文件名
+const DefaultKeyTemplate = "{year}-{month}-{day}-{time}{ext}"
+
+// DefaultDedupKeyTemplate 去重模式下 key_template 不是由文件内容决定时使用的 key 模板
+const DefaultDedupKeyTemplate = "{sha256:16}{ext}"
+
+// randomChars {random} 占位符使用的字符
+const randomChars = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// placeholderPattern 匹配 {name} 或 {name:长度} 形式的占位符
+var placeholderPattern = regexp.MustCompile(`\{([a-z0-9]+)(?::([0-9]+))?\}`)
+
+// keyPlaceholders key 模板支持的占位符
+var keyPlaceholders = map[string]bool{
+	"year": true, "month": true, "day": true, "time": true,
+	"filename": true, "basename": true, "ext": true,
+	"md5": true, "sha256": true, "uuid": true, "random": true,
+}
+
+// KeyData 生成对象 key 时使用的文件信息
+type KeyData struct {
+	// Filename 原文件名，如 screenshot.png
+	Filename string
+	// Time 上传时间
+	Time time.Time
+	// Content 文件内容，用于计算 {md5}、{sha256}
+	Content io.ReaderAt
+	Size    int64
+}
+
+// RenderKey 根据模板生成对象 key
+//
+// 支持的占位符:
+//
+//	{year} {month} {day}   上传日期，如 2024、01、15
+//	{time}                 上传时间，如 143022
+//	{filename}             原文件名，如 screenshot.png
+//	{basename}             不含扩展名的原文件名，如 screenshot
+//	{ext}                  包含 . 的扩展名，如 .png
+//	{md5} {sha256}         文件内容的哈希值，可以指定长度，如 {sha256:8}
+//	{uuid}                 随机 UUID
+//	{random}               随机字符串，默认 8 位，可以指定长度，如 {random:6}
+func RenderKey(template string, data KeyData) (string, error) {
+	ext := path.Ext(data.Filename)
+	hashes := map[string]string{}
+
+	var renderErr error
+	key := placeholderPattern.ReplaceAllStringFunc(template, func(match string) string {
+		groups := placeholderPattern.FindStringSubmatch(match)
+		name, length := groups[1], 0
+		if groups[2] != "" {
+			length, _ = strconv.Atoi(groups[2])
+			if length == 0 {
+				renderErr = fmt.Errorf("占位符 %s 的长度必须大于 0", match)
+				return match
+			}
+		}
+
+		var value string
+		switch name {
+		case "year":
+			value = data.Time.Format("2006")
+		case "month":
+			value = data.Time.Format("01")
+		case "day":
+			value = data.Time.Format("02")
+		case "time":
+			value = data.Time.Format("150405")
+		case "filename":
+			value = data.Filename
+		case "basename":
+			value = strings.TrimSuffix(data.Filename, ext)
+		case "ext":
+			value = ext
+		case "md5", "sha256":
+			sum, ok := hashes[name]
+			if !ok {
+				var err error
+				if sum, err = contentHash(name, data); err != nil {
+					renderErr = err
+					return match
+				}
+				hashes[name] = sum
+			}
+			value = sum
+		case "uuid":
+			uuid, err := newUUID()
+			if err != nil {
+				renderErr = err
+				return match
+			}
+			value = uuid
+		case "random":
+			if length == 0 {
+				length = 8
+			}
+			random, err := randomString(length)
+			if err != nil {
+				renderErr = err
+				return match
+			}
+			value = random
+		default:
+			renderErr = fmt.Errorf("不支持的占位符: %s", match)
+			return match
+		}
+		if length > 0 && length < len(value) {
+			value = value[:length]
+		}
+		return value
+	})
+	if renderErr != nil {
+		return "", renderErr
+	}
+
+	key = strings.TrimLeft(key, "/")
+	if key == "" || strings.HasSuffix(key, "/") {
+		return "", fmt.Errorf("key 模板 %s 生成的 key 无效: %q", template, key)
+	}
+	return key, nil
+}
+
+// CheckKeyTemplate 检查 key 模板中的占位符是否都受支持
+func CheckKeyTemplate(template string) error {
+	if strings.TrimSpace(template) == "" {
+		return fmt.Errorf("key 模板不能为空")
+	}
+	for _, groups := range placeholderPattern.FindAllStringSubmatch(template, -1) {
+		if !keyPlaceholders[groups[1]] {
+			return fmt.Errorf("不支持的占位符: %s", groups[0])
+		}
+	}
+	return nil
+}
+
+// IsContentAddressed 判断模板生成的 key 是否只由文件内容决定，即包含 {md5} 或 {sha256}，
+// 并且除 {ext} 外不包含上传时间、文件名、随机值等其他占位符
+func IsContentAddressed(template string) bool {
+	hasHash := false
+	for _, groups := range placeholderPattern.FindAllStringSubmatch(template, -1) {
+		switch groups[1] {
+		case "md5", "sha256":
+			hasHash = true
+		case "ext":
+		default:
+			return false
+		}
+	}
+	return hasHash
+}
+
+// contentHash 计算文件内容的 md5 或 sha256
+func contentHash(name string, data KeyData) (string, error) {
+	if data.Content == nil {
+		return "", fmt.Errorf("无法计算 {%s}: 没有文件内容", name)
+	}
+	var h hash.Hash = md5.New()
+	if name == "sha256" {
+		h = sha256.New()
+	}
+	if _, err := io.Copy(h, io.NewSectionReader(data.Content, 0, data.Size)); err != nil {
+		return "", fmt.Errorf("计算 %s 失败: %v", name, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// newUUID 生成随机 UUID（version 4）
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("生成 UUID 失败: %v", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// randomString 生成指定长度的随机字符串，由小写字母和数字组成
+func randomString(length int) (string, error) {
+	b := make([]byte, length)
+	max := big.NewInt(int64(len(randomChars)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("生成随机字符串失败: %v", err)
+		}
+		b[i] = randomChars[n.Int64()]
+	}
+	return string(b), nil
+}
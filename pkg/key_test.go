@@ -0,0 +1,68 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderKey(t *testing.T) {
+	content := "hello"
+	data := KeyData{
+		Filename: "screenshot.png",
+		Time:     time.Date(2024, 1, 15, 14, 30, 22, 0, time.Local),
+		Content:  strings.NewReader(content),
+		Size:     int64(len(content)),
+	}
+
+	tests := []struct {
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{DefaultKeyTemplate, "2024-01-15-143022.png", false},
+		{"images/{year}/{month}/{day}/{filename}", "images/2024/01/15/screenshot.png", false},
+		{"{basename}-{time}{ext}", "screenshot-143022.png", false},
+		{"{md5}{ext}", "5d41402abc4b2a76b9719d911017c592.png", false},
+		{"{md5:8}/{md5}", "5d41402a/5d41402abc4b2a76b9719d911017c592", false},
+		{DefaultDedupKeyTemplate, "2cf24dba5fb0a30e.png", false},
+		{"no-placeholder.png", "no-placeholder.png", false},
+		{"{name}{ext}", "", true},
+		{"{sha256:0}", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			got, err := RenderKey(tt.template, data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RenderKey(%q) 的错误为 %v，期望出错: %v", tt.template, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("RenderKey(%q) = %q，期望 %q", tt.template, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderKeyRandom(t *testing.T) {
+	data := KeyData{Filename: "a.png", Time: time.Now()}
+
+	tests := []struct {
+		template string
+		length   int
+	}{
+		{"{random}", 8},
+		{"{random:6}", 6},
+		{"{uuid}", 36},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			got, err := RenderKey(tt.template, data)
+			if err != nil {
+				t.Fatalf("RenderKey(%q) 失败: %v", tt.template, err)
+			}
+			if len(got) != tt.length {
+				t.Errorf("RenderKey(%q) = %q，期望长度为 %d", tt.template, got, tt.length)
+			}
+		})
+	}
+}
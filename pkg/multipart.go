@@ -0,0 +1,471 @@
+package pkg
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc64"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	logger "github.com/bwangelme/cosp/log"
+
+	"github.com/tencentyun/cos-go-sdk-v5"
+)
+
+// MultipartThreshold 文件大小达到该值时自动使用分块上传
+const MultipartThreshold = 16 * 1024 * 1024
+
+// maxPartCount COS 单个分块上传允许的最大分块数
+const maxPartCount = 10000
+
+// Upload 上传对象，数据大小达到 MultipartThreshold 时自动使用分块上传，返回对象的 ETag
+func (c *Client) Upload(ctx context.Context, key string, r io.ReaderAt, size int64, uploadOpt *UploadOptions) (string, error) {
+	if err := c.CheckWritable(); err != nil {
+		return "", err
+	}
+	if size >= MultipartThreshold {
+		logger.L.Debugf("文件大小 %d 字节，使用分块上传", size)
+		return c.MultipartUpload(ctx, key, r, size, uploadOpt)
+	}
+
+	opt := &cos.ObjectPutOptions{
+		ACLHeaderOptions:       uploadOpt.aclHeader(),
+		ObjectPutHeaderOptions: uploadOpt.putHeader(),
+	}
+	opt.ContentLength = size
+
+	var sum checksum
+	if c.Config.Verify == VerifyMD5 {
+		md5sum, err := md5Sum(io.NewSectionReader(r, 0, size))
+		if err != nil {
+			return "", err
+		}
+		sum.md5 = md5sum
+		opt.ContentMD5 = contentMD5(md5sum)
+	}
+
+	attempts := 0
+	resp, err := c.retryTransfer(ctx, "上传文件", func(ctx context.Context) (*cos.Response, error) {
+		attempts++
+		var body io.Reader = io.NewSectionReader(r, 0, size)
+		h := crc64.New(crc64Table)
+		if c.Config.Verify == VerifyCRC64 {
+			// 边上传边计算 CRC64
+			body = io.TeeReader(body, h)
+		}
+		resp, err := c.Object.Put(ctx, key, body, opt)
+		sum.crc64 = h.Sum64()
+		return resp, err
+	})
+	if err != nil {
+		// 内容已经与本地比较过，不需要再校验
+		resp, err := c.resolveConflict(ctx, key, err, attempts > 1, r, size)
+		if err != nil {
+			return "", err
+		}
+		return objectETag(resp.Header.Get("ETag")), nil
+	}
+	if err := c.verifyObject(key, resp.Header, sum); err != nil {
+		return "", err
+	}
+	return objectETag(resp.Header.Get("ETag")), nil
+}
+
+// UploadFile 上传本地文件，返回实际使用的对象 key 和对象的 ETag
+//
+// resume 为 true 时大文件使用断点续传：已完成的分块记录在本地断点文件中，
+// 再次以相同的 key 上传同一文件时沿用之前的 UploadId，只上传缺失的分块。
+func (c *Client) UploadFile(ctx context.Context, key string, file *os.File, resume bool, uploadOpt *UploadOptions) (string, string, error) {
+	if err := c.CheckWritable(); err != nil {
+		return "", "", err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		return "", "", fmt.Errorf("获取文件信息失败: %v", err)
+	}
+	size := stat.Size()
+
+	if !resume || size < MultipartThreshold {
+		etag, err := c.Upload(ctx, key, file, size, uploadOpt)
+		return key, etag, err
+	}
+
+	cp, err := NewCheckpoint(file)
+	if err != nil {
+		return "", "", fmt.Errorf("创建断点记录失败: %v", err)
+	}
+	if err := c.prepareCheckpoint(ctx, cp, key, size, uploadOpt); err != nil {
+		return "", "", err
+	}
+
+	parts, err := c.uploadParts(ctx, cp.Key, cp.UploadID, file, size, cp.PartSize, cp.Parts, func(part cos.Object) {
+		if err := cp.SetPart(part.PartNumber, part.ETag); err != nil {
+			logger.L.Debugf("保存断点文件失败: %v", err)
+		}
+	})
+	if err != nil {
+		// 保留断点文件和已上传的分块，下次上传时继续
+		return cp.Key, "", fmt.Errorf("%v（已保存上传进度，重新执行上传命令可继续）", err)
+	}
+
+	resp, etag, err := c.completeMultipartUpload(ctx, cp.Key, cp.UploadID, parts, uploadOpt, file, size)
+	if errors.Is(err, ErrObjectExists) {
+		// 文件已存在时无法继续使用这次分块上传，舍弃后删除断点记录
+		c.abortMultipartUpload(cp.Key, cp.UploadID)
+		if err := cp.Remove(); err != nil {
+			logger.L.Debugf("删除断点文件失败: %v", err)
+		}
+	}
+	if err != nil {
+		return cp.Key, "", err
+	}
+	if err := cp.Remove(); err != nil {
+		logger.L.Debugf("删除断点文件失败: %v", err)
+	}
+	if err := c.verifyMultipart(cp.Key, resp.Header, file, size); err != nil {
+		return cp.Key, "", err
+	}
+	return cp.Key, etag, nil
+}
+
+// prepareCheckpoint 加载可用的断点记录，没有时初始化新的分块上传
+func (c *Client) prepareCheckpoint(ctx context.Context, cp *Checkpoint, key string, size int64, uploadOpt *UploadOptions) error {
+	found, err := cp.Load()
+	if err != nil {
+		logger.L.Debugf("读取断点文件失败，重新上传: %v", err)
+	}
+	if found && err == nil && cp.Bucket == c.Config.Bucket && cp.PartSize > 0 && c.resumeCheckpoint(ctx, cp, key, uploadOpt) {
+		return nil
+	}
+
+	uploadID, err := c.initiateMultipartUpload(ctx, key, uploadOpt)
+	if err != nil {
+		return err
+	}
+	cp.Bucket = c.Config.Bucket
+	cp.Key = key
+	cp.UploadID = uploadID
+	cp.PartSize = c.partSize(size)
+	cp.Parts = map[int]string{}
+	cp.Options = uploadOpt.fingerprint()
+	logger.L.Debugf("初始化分块上传成功，UploadId: %s", cp.UploadID)
+
+	if err := cp.Save(); err != nil {
+		logger.L.Debugf("保存断点文件失败: %v", err)
+	}
+	return nil
+}
+
+// resumeCheckpoint 判断断点记录能否继续使用，能使用时加载已上传的分块，
+// 不能使用时舍弃之前的分块上传
+func (c *Client) resumeCheckpoint(ctx context.Context, cp *Checkpoint, key string, uploadOpt *UploadOptions) bool {
+	// 继续上传只能写入之前的 key，调用方指定了不同的 key 时不能使用
+	if cp.Key != key {
+		logger.L.Debugf("文件名与断点记录 %s 不同，舍弃之前的分块上传 %s 并重新上传", cp.Key, cp.UploadID)
+		c.abortMultipartUpload(cp.Key, cp.UploadID)
+		return false
+	}
+	// ACL、Content-Type 等在初始化分块上传时确定，参数改变后继续上传会使用之前的参数
+	if cp.Options != uploadOpt.fingerprint() {
+		logger.L.Debugf("上传参数与断点记录不同，舍弃之前的分块上传 %s 并重新上传", cp.UploadID)
+		c.abortMultipartUpload(cp.Key, cp.UploadID)
+		return false
+	}
+	uploaded, err := c.listUploadedParts(ctx, cp.Key, cp.UploadID)
+	if err != nil {
+		logger.L.Debugf("断点记录已失效，重新上传: %v", err)
+		return false
+	}
+	cp.Parts = uploaded
+	logger.L.Debugf("找到断点记录，UploadId: %s，已上传 %d 个分块", cp.UploadID, len(uploaded))
+	return true
+}
+
+// listUploadedParts 查询分块上传中已上传的分块
+func (c *Client) listUploadedParts(ctx context.Context, key, uploadID string) (map[int]string, error) {
+	parts := map[int]string{}
+	opt := &cos.ObjectListPartsOptions{MaxParts: "1000"}
+	for {
+		var res *cos.ObjectListPartsResult
+		_, err := c.retry(ctx, "查询已上传分块", func(ctx context.Context) (resp *cos.Response, err error) {
+			res, resp, err = c.Object.ListParts(ctx, key, uploadID, opt)
+			return resp, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range res.Parts {
+			parts[part.PartNumber] = part.ETag
+		}
+		if !res.IsTruncated {
+			return parts, nil
+		}
+		opt.PartNumberMarker = res.NextPartNumberMarker
+	}
+}
+
+// MultipartUpload 将数据按 PartSize 切分，使用 MaxThread 个协程并发上传分块后合并，返回对象的 ETag
+func (c *Client) MultipartUpload(ctx context.Context, key string, r io.ReaderAt, size int64, uploadOpt *UploadOptions) (string, error) {
+	if err := c.CheckWritable(); err != nil {
+		return "", err
+	}
+	uploadID, err := c.initiateMultipartUpload(ctx, key, uploadOpt)
+	if err != nil {
+		return "", err
+	}
+
+	parts, err := c.uploadParts(ctx, key, uploadID, r, size, c.partSize(size), nil, nil)
+	if err != nil {
+		// 上传失败时舍弃本次分块上传，避免残留的分块占用存储空间
+		c.abortMultipartUpload(key, uploadID)
+		return "", err
+	}
+
+	resp, etag, err := c.completeMultipartUpload(ctx, key, uploadID, parts, uploadOpt, r, size)
+	if err != nil {
+		if errors.Is(err, ErrObjectExists) {
+			c.abortMultipartUpload(key, uploadID)
+		}
+		return "", err
+	}
+	if err := c.verifyMultipart(key, resp.Header, r, size); err != nil {
+		return "", err
+	}
+	return etag, nil
+}
+
+// initiateMultipartUpload 初始化分块上传，返回 UploadId
+func (c *Client) initiateMultipartUpload(ctx context.Context, key string, uploadOpt *UploadOptions) (string, error) {
+	opt := &cos.InitiateMultipartUploadOptions{
+		ACLHeaderOptions:       uploadOpt.aclHeader(),
+		ObjectPutHeaderOptions: uploadOpt.putHeader(),
+	}
+	var res *cos.InitiateMultipartUploadResult
+	_, err := c.retry(ctx, "初始化分块上传", func(ctx context.Context) (resp *cos.Response, err error) {
+		res, resp, err = c.Object.InitiateMultipartUpload(ctx, key, opt)
+		return resp, err
+	})
+	if err != nil {
+		return "", fmt.Errorf("初始化分块上传失败: %v", err)
+	}
+	logger.L.Debugf("初始化分块上传成功，UploadId: %s", res.UploadID)
+	return res.UploadID, nil
+}
+
+// completeMultipartUpload 合并已上传的分块，返回响应和合并后对象的 ETag。
+// r 和 size 为上传的内容，用于判断重试时遇到的 409
+func (c *Client) completeMultipartUpload(ctx context.Context, key, uploadID string, parts []cos.Object, uploadOpt *UploadOptions, r io.ReaderAt, size int64) (*cos.Response, string, error) {
+	attempts := 0
+	var res *cos.CompleteMultipartUploadResult
+	resp, err := c.retryTransfer(ctx, "完成分块上传", func(ctx context.Context) (resp *cos.Response, err error) {
+		attempts++
+		res, resp, err = c.Object.CompleteMultipartUpload(ctx, key, uploadID, &cos.CompleteMultipartUploadOptions{
+			Parts:         parts,
+			XOptionHeader: uploadOpt.header(),
+		})
+		return resp, err
+	})
+	if err != nil {
+		// 重试前的请求合并成功时，分块上传已经不存在
+		if attempts > 1 && isNoSuchUpload(err) {
+			if resp, same := c.sameContent(ctx, key, r, size); same {
+				logger.L.Debugf("重试前的请求已经完成分块上传: %s", key)
+				return resp, objectETag(resp.Header.Get("ETag")), nil
+			}
+		}
+		resp, err := c.resolveConflict(ctx, key, err, attempts > 1, r, size)
+		if err == nil {
+			return resp, objectETag(resp.Header.Get("ETag")), nil
+		}
+		if errors.Is(err, ErrObjectExists) {
+			return nil, "", err
+		}
+		return nil, "", fmt.Errorf("完成分块上传失败: %v", err)
+	}
+	return resp, objectETag(res.ETag), nil
+}
+
+// objectETag 去掉 ETag 两侧的引号
+func objectETag(etag string) string {
+	return strings.Trim(etag, "\"")
+}
+
+// isNoSuchUpload 判断错误是否为分块上传不存在
+func isNoSuchUpload(err error) bool {
+	cosErr, ok := cos.IsCOSError(err)
+	return ok && cosErr.Code == "NoSuchUpload"
+}
+
+// abortMultipartUpload 舍弃失败的分块上传，避免残留的分块占用存储空间。
+// ctx 可能已经被 Ctrl-C 取消，这里使用新的 context 保证清理请求能够发出
+func (c *Client) abortMultipartUpload(key, uploadID string) {
+	if err := c.AbortMultipartUpload(context.Background(), key, uploadID); err != nil {
+		logger.L.Debugf("舍弃分块上传失败: %v", err)
+	}
+}
+
+// uploadParts 并发上传 done 中不存在的分块，返回按编号排列的全部分块信息
+//
+// 每个分块上传完成后会调用 onPart，调用方可以借此记录上传进度。
+func (c *Client) uploadParts(ctx context.Context, key, uploadID string, r io.ReaderAt, size, partSize int64, done map[int]string, onPart func(cos.Object)) ([]cos.Object, error) {
+	partCount := int((size + partSize - 1) / partSize)
+
+	var (
+		mu    sync.Mutex
+		parts []cos.Object
+		todo  []int
+	)
+	for partNumber := 1; partNumber <= partCount; partNumber++ {
+		if etag, ok := done[partNumber]; ok {
+			offset, length := partRange(partNumber, partSize, size)
+			if c.partUploaded(r, offset, length, etag) {
+				parts = append(parts, cos.Object{PartNumber: partNumber, ETag: etag})
+				continue
+			}
+			logger.L.Debugf("已上传的第 %d 个分块与本地内容不一致，重新上传", partNumber)
+		}
+		todo = append(todo, partNumber)
+	}
+	if len(parts) > 0 {
+		logger.L.Debugf("跳过 %d 个已上传的分块", len(parts))
+	}
+
+	err := runParts(ctx, todo, c.Config.MaxThread, func(ctx context.Context, partNumber int) error {
+		offset, length := partRange(partNumber, partSize, size)
+		resp, err := c.uploadPart(ctx, key, uploadID, partNumber, r, offset, length)
+		if err != nil {
+			return fmt.Errorf("上传第 %d 个分块失败: %v", partNumber, err)
+		}
+
+		part := cos.Object{
+			PartNumber: partNumber,
+			ETag:       resp.Header.Get("ETag"),
+		}
+		mu.Lock()
+		parts = append(parts, part)
+		mu.Unlock()
+		if onPart != nil {
+			onPart(part)
+		}
+		logger.L.Debugf("分块 %d/%d 上传完成，大小: %d 字节", partNumber, partCount, length)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(cos.ObjectList(parts))
+	return parts, nil
+}
+
+// uploadPart 上传一个分块，MD5 校验模式下会校验 COS 返回的 ETag
+func (c *Client) uploadPart(ctx context.Context, key, uploadID string, partNumber int, r io.ReaderAt, offset, length int64) (*cos.Response, error) {
+	opt := &cos.ObjectUploadPartOptions{
+		ContentLength: length,
+	}
+
+	var partMD5 []byte
+	if c.Config.Verify == VerifyMD5 {
+		sum, err := md5Sum(io.NewSectionReader(r, offset, length))
+		if err != nil {
+			return nil, err
+		}
+		partMD5 = sum
+		opt.ContentMD5 = contentMD5(sum)
+	}
+
+	resp, err := c.retryTransfer(ctx, fmt.Sprintf("上传第 %d 个分块", partNumber), func(ctx context.Context) (*cos.Response, error) {
+		return c.Object.UploadPart(ctx, key, uploadID, partNumber, io.NewSectionReader(r, offset, length), opt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if partMD5 != nil {
+		if err := checkETag(resp.Header, partMD5); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// partUploaded 判断断点记录中已上传的分块是否可以沿用，MD5 校验模式下会比较本地内容与 ETag
+func (c *Client) partUploaded(r io.ReaderAt, offset, length int64, etag string) bool {
+	if c.Config.Verify != VerifyMD5 {
+		return true
+	}
+	sum, err := md5Sum(io.NewSectionReader(r, offset, length))
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(strings.Trim(etag, `"`), hex.EncodeToString(sum))
+}
+
+// runParts 使用最多 concurrency 个 goroutine 并发处理 partNumbers 中的分块
+//
+// 任意一个分块失败时取消其余分块并返回第一个错误，ctx 被取消时返回 ctx.Err()。
+func runParts(ctx context.Context, partNumbers []int, concurrency int, fn func(ctx context.Context, partNumber int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	workers := max(min(concurrency, len(partNumbers)), 1)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for partNumber := range jobs {
+				if err := fn(ctx, partNumber); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+	for _, partNumber := range partNumbers {
+		select {
+		case jobs <- partNumber:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// partRange 计算分块在数据中的偏移量和长度
+func partRange(partNumber int, partSize, size int64) (int64, int64) {
+	offset := int64(partNumber-1) * partSize
+	length := partSize
+	if offset+length > size {
+		length = size - offset
+	}
+	return offset, length
+}
+
+// partSize 计算分块大小，保证分块数不超过 COS 的限制
+func (c *Client) partSize(size int64) int64 {
+	partSize := int64(c.Config.PartSize) * 1024 * 1024
+	if partSize <= 0 {
+		partSize = 1024 * 1024
+	}
+	for (size+partSize-1)/partSize > maxPartCount {
+		partSize *= 2
+	}
+	return partSize
+}
@@ -0,0 +1,107 @@
+package pkg
+
+import (
+	"context"
+
+	"github.com/tencentyun/cos-go-sdk-v5"
+)
+
+// ListObjects 列出 bucket 中的文件
+func (c *Client) ListObjects(ctx context.Context, opt *cos.BucketGetOptions) (*cos.BucketGetResult, error) {
+	var result *cos.BucketGetResult
+	_, err := c.retry(ctx, "获取文件列表", func(ctx context.Context) (resp *cos.Response, err error) {
+		result, resp, err = c.Bucket.Get(ctx, opt)
+		return resp, err
+	})
+	return result, err
+}
+
+// DeleteObject 删除指定文件
+func (c *Client) DeleteObject(ctx context.Context, key string) (*cos.Response, error) {
+	if err := c.CheckWritable(); err != nil {
+		return nil, err
+	}
+	return c.retry(ctx, "删除文件", func(ctx context.Context) (*cos.Response, error) {
+		return c.Object.Delete(ctx, key)
+	})
+}
+
+// ListMultipartUploads 列出 bucket 中所有未完成的分块上传
+func (c *Client) ListMultipartUploads(ctx context.Context, prefix string) ([]cos.ListUploadsResultUpload, error) {
+	var uploads []cos.ListUploadsResultUpload
+	opt := &cos.ObjectListUploadsOptions{Prefix: prefix}
+	for {
+		var result *cos.ObjectListUploadsResult
+		_, err := c.retry(ctx, "获取分块上传列表", func(ctx context.Context) (resp *cos.Response, err error) {
+			result, resp, err = c.Object.ListUploads(ctx, opt)
+			return resp, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, result.Upload...)
+		if !result.IsTruncated {
+			return uploads, nil
+		}
+		opt.KeyMarker = result.NextKeyMarker
+		opt.UploadIdMarker = result.NextUploadIdMarker
+	}
+}
+
+// AbortMultipartUpload 舍弃分块上传并删除已上传的分块
+func (c *Client) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	if err := c.CheckWritable(); err != nil {
+		return err
+	}
+	_, err := c.retry(ctx, "舍弃分块上传", func(ctx context.Context) (*cos.Response, error) {
+		return c.Object.AbortMultipartUpload(ctx, key, uploadID)
+	})
+	return err
+}
+
+// HeadObject 获取文件的元信息
+func (c *Client) HeadObject(ctx context.Context, key string) (*cos.Response, error) {
+	return c.retry(ctx, "获取文件信息", func(ctx context.Context) (*cos.Response, error) {
+		return c.Object.Head(ctx, key, nil)
+	})
+}
+
+// ObjectExists 检查文件是否存在
+func (c *Client) ObjectExists(ctx context.Context, key string) (bool, error) {
+	_, err := c.HeadObject(ctx, key)
+	if err == nil {
+		return true, nil
+	}
+	if cos.IsNotFoundError(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// HeadBucket 检查 bucket 是否存在以及当前密钥是否有访问权限
+func (c *Client) HeadBucket(ctx context.Context) error {
+	_, err := c.retry(ctx, "检查 bucket", func(ctx context.Context) (*cos.Response, error) {
+		return c.Bucket.Head(ctx)
+	})
+	return err
+}
+
+// ListAllObjects 分页列出 bucket 中以 prefix 开头的所有文件
+func (c *Client) ListAllObjects(ctx context.Context, prefix string) ([]cos.Object, error) {
+	var objects []cos.Object
+	opt := &cos.BucketGetOptions{Prefix: prefix, MaxKeys: 1000}
+	for {
+		result, err := c.ListObjects(ctx, opt)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, result.Contents...)
+		if !result.IsTruncated {
+			return objects, nil
+		}
+		opt.Marker = result.NextMarker
+		if opt.Marker == "" && len(result.Contents) > 0 {
+			opt.Marker = result.Contents[len(result.Contents)-1].Key
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package pkg
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tencentyun/cos-go-sdk-v5"
+)
+
+// storageClasses COS 支持的存储类型
+var storageClasses = []string{
+	"STANDARD",
+	"STANDARD_IA",
+	"INTELLIGENT_TIERING",
+	"ARCHIVE",
+	"DEEP_ARCHIVE",
+	"MAZ_STANDARD",
+	"MAZ_STANDARD_IA",
+	"MAZ_INTELLIGENT_TIERING",
+}
+
+// metaKeyPattern 自定义元数据的名称，只能包含字母、数字和 -
+var metaKeyPattern = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+
+// UploadOptions 上传文件时的可选参数，nil 表示使用默认值
+type UploadOptions struct {
+	// ForbidOverwrite 为 true 时 COS 中已存在同名文件则上传失败，返回 ErrObjectExists
+	ForbidOverwrite bool
+	// ContentType 文件的 Content-Type，为空时由 COS 根据扩展名判断
+	ContentType        string
+	CacheControl       string
+	ContentDisposition string
+	// Expires HTTP 日期格式的过期时间，可以使用 ParseExpires 生成
+	Expires      string
+	StorageClass string
+	// Meta 自定义元数据，上传时添加 x-cos-meta- 前缀
+	Meta map[string]string
+	// ACL 文件的访问权限，为空或 default 时继承 bucket 的访问权限
+	ACL string
+}
+
+// header 返回 SDK 没有提供字段的请求头
+func (o *UploadOptions) header() *http.Header {
+	header := http.Header{}
+	if o != nil && o.ForbidOverwrite {
+		header.Set("x-cos-forbid-overwrite", "true")
+	}
+	return &header
+}
+
+// putHeader 返回简单上传和初始化分块上传时使用的请求头
+func (o *UploadOptions) putHeader() *cos.ObjectPutHeaderOptions {
+	opt := &cos.ObjectPutHeaderOptions{XOptionHeader: o.header()}
+	if o == nil {
+		return opt
+	}
+	opt.ContentType = o.ContentType
+	opt.CacheControl = o.CacheControl
+	opt.ContentDisposition = o.ContentDisposition
+	opt.Expires = o.Expires
+	opt.XCosStorageClass = o.StorageClass
+	if len(o.Meta) > 0 {
+		meta := http.Header{}
+		for key, value := range o.Meta {
+			meta.Set("x-cos-meta-"+key, value)
+		}
+		opt.XCosMetaXXX = &meta
+	}
+	return opt
+}
+
+// aclHeader 返回设置访问权限的请求头
+func (o *UploadOptions) aclHeader() *cos.ACLHeaderOptions {
+	if o == nil || o.ACL == "" || o.ACL == ACLDefault {
+		return nil
+	}
+	return &cos.ACLHeaderOptions{XCosACL: o.ACL}
+}
+
+// fingerprint 返回初始化分块上传时确定的对象属性的摘要，用于判断断点记录能否继续使用
+//
+// 不包含 Expires：相对时长每次上传时计算出的日期都不同，包含后断点续传永远不会生效。
+func (o *UploadOptions) fingerprint() string {
+	if o == nil {
+		o = &UploadOptions{}
+	}
+	acl := o.ACL
+	if acl == ACLDefault {
+		acl = ""
+	}
+	h := sha1.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n%s\n", acl, o.ContentType, o.CacheControl, o.ContentDisposition, o.StorageClass)
+	keys := make([]string, 0, len(o.Meta))
+	for key := range o.Meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(h, "%s=%s\n", strings.ToLower(key), o.Meta[key])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// UploadOptions 返回配置中的默认上传参数，Expires 按 now 计算
+func (c *COSConfig) UploadOptions(now time.Time) *UploadOptions {
+	opt := &UploadOptions{
+		CacheControl:       c.CacheControl,
+		ContentDisposition: c.ContentDisposition,
+		StorageClass:       c.StorageClass,
+		Meta:               map[string]string{},
+	}
+	if c.Expires != "" {
+		// 配置读取时已经检查过格式
+		opt.Expires, _ = ParseExpires(c.Expires, now)
+	}
+	for key, value := range c.Meta {
+		opt.Meta[key] = value
+	}
+	return opt
+}
+
+// ParseStorageClass 检查存储类型，不区分大小写
+func ParseStorageClass(value string) (string, error) {
+	class := strings.ToUpper(value)
+	for _, c := range storageClasses {
+		if class == c {
+			return class, nil
+		}
+	}
+	return "", fmt.Errorf("可选值: %s", strings.Join(storageClasses, ", "))
+}
+
+// ParseExpires 将过期时间转换为 Expires 请求头使用的 HTTP 日期格式
+//
+// 支持 HTTP 日期（如 Mon, 02 Jan 2006 15:04:05 GMT）和相对上传时间的时长（如 720h、30d）。
+func ParseExpires(value string, now time.Time) (string, error) {
+	if t, err := http.ParseTime(value); err == nil {
+		return t.UTC().Format(http.TimeFormat), nil
+	}
+	var d time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return "", fmt.Errorf("格式不正确，应为 HTTP 日期或时长，如 720h、30d")
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(value); err != nil || d <= 0 {
+			return "", fmt.Errorf("格式不正确，应为 HTTP 日期或时长，如 720h、30d")
+		}
+	}
+	return now.Add(d).UTC().Format(http.TimeFormat), nil
+}
+
+// ParseMeta 解析 key=value 形式的自定义元数据，多个元数据之间使用逗号分隔
+func ParseMeta(values ...string) (map[string]string, error) {
+	meta := map[string]string{}
+	for _, value := range values {
+		for _, pair := range strings.Split(value, ",") {
+			pair = strings.TrimSpace(pair)
+			if pair == "" {
+				continue
+			}
+			key, val, ok := strings.Cut(pair, "=")
+			key = strings.TrimSpace(key)
+			if !ok || !metaKeyPattern.MatchString(key) {
+				return nil, fmt.Errorf("自定义元数据 %q 格式不正确，应为 key=value，key 只能包含字母、数字和 -", pair)
+			}
+			meta[strings.ToLower(key)] = strings.TrimSpace(val)
+		}
+	}
+	return meta, nil
+}
+
+// formatMeta 将自定义元数据格式化为 key=value,key=value
+func formatMeta(meta map[string]string) string {
+	pairs := make([]string, 0, len(meta))
+	for key, value := range meta {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,33 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/tencentyun/cos-go-sdk-v5"
+)
+
+// PresignedURL 生成带签名的临时地址，在 expires 时间内可以不使用密钥以 method 方法访问对象
+//
+// 地址使用 bucket 默认域名而不是 url_base。使用临时密钥时，地址在临时密钥过期后也会失效。
+func (c *Client) PresignedURL(ctx context.Context, method, key string, expires time.Duration) (string, error) {
+	if c.credentials == nil {
+		return "", ErrAnonymous
+	}
+	cred, err := c.credentials.Retrieve(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var opt any
+	if cred.SessionToken != "" {
+		opt = &cos.PresignedURLOptions{Query: &url.Values{"x-cos-security-token": {cred.SessionToken}}}
+	}
+	u, err := c.Object.GetPresignedURL(ctx, method, key, cred.SecretID, cred.SecretKey, expires, opt)
+	if err != nil {
+		return "", fmt.Errorf("生成签名地址失败: %v", err)
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,121 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	logger "github.com/bwangelme/cosp/log"
+
+	"github.com/tencentyun/cos-go-sdk-v5"
+)
+
+const (
+	// retryBaseDelay 第一次重试前的等待时间
+	retryBaseDelay = 200 * time.Millisecond
+	// retryMaxDelay 两次重试之间的最长等待时间
+	retryMaxDelay = 10 * time.Second
+)
+
+// retry 执行一次 COS 请求，遇到可重试的错误时按指数退避重试，最多重试 Config.Retry 次
+//
+// 每次请求都使用独立的超时时间 Config.Timeout。
+func (c *Client) retry(ctx context.Context, op string, fn func(ctx context.Context) (*cos.Response, error)) (*cos.Response, error) {
+	return c.doRetry(ctx, op, c.Config.timeout(), fn)
+}
+
+// retryTransfer 与 retry 相同，但不限制单次请求的总时长，用于上传文件内容等耗时与数据量相关的请求
+//
+// fn 每次被调用时都必须重新构造请求内容（如重新创建 io.SectionReader），
+// 保证重试时发送的是完整的数据。
+func (c *Client) retryTransfer(ctx context.Context, op string, fn func(ctx context.Context) (*cos.Response, error)) (*cos.Response, error) {
+	return c.doRetry(ctx, op, 0, fn)
+}
+
+func (c *Client) doRetry(ctx context.Context, op string, timeout time.Duration, fn func(ctx context.Context) (*cos.Response, error)) (*cos.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := c.attempt(ctx, timeout, fn)
+		if err == nil || attempt >= c.Config.Retry || !isRetryable(ctx, err) {
+			return resp, err
+		}
+
+		delay := backoff(attempt)
+		logger.L.Debugf("%s失败，%v 后进行第 %d/%d 次重试: %v", op, delay, attempt+1, c.Config.Retry, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, err
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt 执行一次请求，timeout 大于 0 时为本次请求设置超时时间
+func (c *Client) attempt(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) (*cos.Response, error)) (*cos.Response, error) {
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return fn(ctx)
+}
+
+// isRetryable 判断错误是否为可重试的临时错误
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	// 单次请求超时
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	if cosErr, ok := cos.IsCOSError(err); ok {
+		if cosErr.Response == nil {
+			return false
+		}
+		switch code := cosErr.Response.StatusCode; {
+		case code >= 500:
+			return true
+		case code == http.StatusTooManyRequests, code == http.StatusRequestTimeout:
+			return true
+		default:
+			return false
+		}
+	}
+	// SDK 会把网络错误包装在 RetryError 中
+	if retryErr, ok := err.(*cos.RetryError); ok && len(retryErr.Errs) > 0 {
+		err = retryErr.Errs[len(retryErr.Errs)-1]
+	}
+	// 获取密钥失败（如 credential_process 执行失败），重试也不会成功
+	if errors.Is(err, ErrCredential) {
+		return false
+	}
+	// 下载时读取响应内容的过程中连接中断
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	// 连接被重置或拒绝
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	// 读写超时。*url.Error 也实现了 net.Error，DNS 解析失败、证书错误等不是超时的网络错误不重试
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// backoff 计算第 attempt 次重试前的等待时间，在指数退避的基础上加入随机抖动
+func backoff(attempt int) time.Duration {
+	delay := retryBaseDelay << uint(attempt)
+	if delay <= 0 || delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
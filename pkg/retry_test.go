@@ -0,0 +1,74 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/tencentyun/cos-go-sdk-v5"
+)
+
+// timeoutError 模拟读写超时的 net.Error
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsRetryable(t *testing.T) {
+	urlErr := func(err error) error {
+		return &url.Error{Op: "Put", URL: "https://b-1250000000.cos.ap-beijing.myqcloud.com/a.png", Err: err}
+	}
+	cosErr := func(status int) error {
+		return &cos.ErrorResponse{Response: &http.Response{StatusCode: status}}
+	}
+	opErr := func(err error) error {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", err)}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"单次请求超时", context.DeadlineExceeded, true},
+		{"读写超时", urlErr(timeoutError{}), true},
+		{"连接被重置", urlErr(opErr(syscall.ECONNRESET)), true},
+		{"连接被拒绝", urlErr(opErr(syscall.ECONNREFUSED)), true},
+		{"读取响应时连接中断", fmt.Errorf("读取响应失败: %w", io.ErrUnexpectedEOF), true},
+		{"SDK 包装的网络错误", &cos.RetryError{Errs: []error{urlErr(opErr(syscall.ECONNRESET))}}, true},
+		{"DNS 解析失败", urlErr(&net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}), false},
+		{"其他网络错误", urlErr(errors.New("x509: certificate signed by unknown authority")), false},
+		{"获取密钥失败", urlErr(fmt.Errorf("%w: exit status 1", ErrCredential)), false},
+		{"COS 503", cosErr(http.StatusServiceUnavailable), true},
+		{"COS 500", cosErr(http.StatusInternalServerError), true},
+		{"COS 429", cosErr(http.StatusTooManyRequests), true},
+		{"COS 408", cosErr(http.StatusRequestTimeout), true},
+		{"COS 404", cosErr(http.StatusNotFound), false},
+		{"COS 403", cosErr(http.StatusForbidden), false},
+		{"COS 错误没有响应", &cos.ErrorResponse{}, false},
+		{"普通错误", errors.New("打开文件失败"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(context.Background(), tt.err); got != tt.want {
+				t.Errorf("isRetryable(%v) = %v，期望 %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRetryableCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if isRetryable(ctx, context.DeadlineExceeded) {
+		t.Error("ctx 被取消后不应该重试")
+	}
+}
@@ -0,0 +1,122 @@
+package pkg
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash/crc64"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	logger "github.com/bwangelme/cosp/log"
+)
+
+// 上传后的校验方式，对应配置项 verify
+const (
+	VerifyMD5   = "md5"
+	VerifyCRC64 = "crc64"
+	VerifyNone  = "none"
+)
+
+// crc64Table COS 使用 ECMA 多项式计算 x-cos-hash-crc64ecma
+var crc64Table = crc64.MakeTable(crc64.ECMA)
+
+// checksum 记录上传内容在本地计算出的校验值
+type checksum struct {
+	md5   []byte
+	crc64 uint64
+}
+
+// md5Sum 计算数据的 MD5
+func md5Sum(r io.Reader) ([]byte, error) {
+	h := md5.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, fmt.Errorf("计算 MD5 失败: %v", err)
+	}
+	return h.Sum(nil), nil
+}
+
+// crc64Sum 计算数据的 CRC64
+func crc64Sum(r io.Reader) (uint64, error) {
+	h := crc64.New(crc64Table)
+	if _, err := io.Copy(h, r); err != nil {
+		return 0, fmt.Errorf("计算 CRC64 失败: %v", err)
+	}
+	return h.Sum64(), nil
+}
+
+// contentMD5 返回 Content-MD5 请求头使用的 base64 编码
+func contentMD5(sum []byte) string {
+	return base64.StdEncoding.EncodeToString(sum)
+}
+
+// checkETag 校验 COS 返回的 ETag 是否与本地 MD5 一致
+func checkETag(header http.Header, sum []byte) error {
+	etag := strings.Trim(header.Get("ETag"), `"`)
+	if etag == "" {
+		logger.L.Warn("COS 未返回 ETag，跳过 MD5 校验")
+		return nil
+	}
+	local := hex.EncodeToString(sum)
+	if !strings.EqualFold(etag, local) {
+		return fmt.Errorf("MD5 校验失败，本地: %s，COS: %s", local, etag)
+	}
+	logger.L.Debugf("MD5 校验通过: %s", local)
+	return nil
+}
+
+// checkCRC64 校验 COS 返回的 x-cos-hash-crc64ecma 是否与本地 CRC64 一致
+func checkCRC64(header http.Header, sum uint64) error {
+	value := header.Get("x-cos-hash-crc64ecma")
+	if value == "" {
+		logger.L.Warn("COS 未返回 x-cos-hash-crc64ecma，跳过 CRC64 校验")
+		return nil
+	}
+	remote, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("解析 COS 返回的 CRC64 失败: %v", err)
+	}
+	if remote != sum {
+		return fmt.Errorf("CRC64 校验失败，本地: %d，COS: %d", sum, remote)
+	}
+	logger.L.Debugf("CRC64 校验通过: %d", sum)
+	return nil
+}
+
+// verifyObject 按配置的校验方式检查上传结果，校验失败时删除已上传的对象
+func (c *Client) verifyObject(key string, header http.Header, sum checksum) error {
+	var err error
+	switch c.Config.Verify {
+	case VerifyMD5:
+		err = checkETag(header, sum.md5)
+	case VerifyCRC64:
+		err = checkCRC64(header, sum.crc64)
+	}
+	if err == nil {
+		return nil
+	}
+
+	// 上传的对象内容与本地不一致，删除后返回错误。ctx 可能已经被取消，使用新的 context
+	if _, delErr := c.DeleteObject(context.Background(), key); delErr != nil {
+		return fmt.Errorf("%v，删除损坏的对象 %s 失败: %v", err, key, delErr)
+	}
+	return fmt.Errorf("%v，已删除损坏的对象 %s", err, key)
+}
+
+// verifyMultipart 校验分块上传合并后的对象
+//
+// MD5 校验模式下每个分块在上传时已经单独校验，合并后对象的 ETag 不是内容的 MD5，这里只处理 CRC64。
+func (c *Client) verifyMultipart(key string, header http.Header, r io.ReaderAt, size int64) error {
+	if c.Config.Verify != VerifyCRC64 {
+		return nil
+	}
+	sum, err := crc64Sum(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return err
+	}
+	return c.verifyObject(key, header, checksum{crc64: sum})
+}
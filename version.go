@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"runtime"
+
+	"github.com/bwangelme/cosp/cmd"
 )
 
 // Version 版本信息
@@ -14,8 +16,29 @@ var BuildTime = "unknown"
 // GitCommit Git提交哈希，会在编译时通过 -ldflags 注入
 var GitCommit = "unknown"
 
+// versionInfo 结构化输出的版本信息
+type versionInfo struct {
+	Version   string `json:"version"`
+	BuildTime string `json:"build_time"`
+	GitCommit string `json:"git_commit"`
+	GoVersion string `json:"go_version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+}
+
 // PrintVersion 打印版本信息
 func PrintVersion() {
+	if cmd.StructuredOutput() {
+		cmd.PrintRecord(versionInfo{
+			Version:   Version,
+			BuildTime: BuildTime,
+			GitCommit: GitCommit,
+			GoVersion: runtime.Version(),
+			OS:        runtime.GOOS,
+			Arch:      runtime.GOARCH,
+		})
+		return
+	}
 	fmt.Printf("COSP - 腾讯云 COS 图片上传工具\n")
 	fmt.Printf("Version: %s\n", Version)
 	fmt.Printf("Build Time: %s\n", BuildTime)